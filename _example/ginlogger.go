@@ -33,12 +33,16 @@ func main() {
 		EnableRequestForm:   false,       // 记录 request form
 		EnableRequestBody:   false,       // 记录 request body
 		EnableResponseBody:  false,       // 记录 response body
+		RequestBodyMaxSize:  4096,        // request body 最多记录的字节数，超出截断
+		ResponseBodyMaxSize: 4096,        // response body 最多记录的字节数，超出截断
 		SlowThreshold:       time.Second, // 慢查询阈值，超时这个时间会答应 Warn 日志
 		OutputPaths:         []string{"stdout", "lumberjack:"},
 		InitialFields:       map[string]interface{}{"key1": "value1"}, // 一些初始化的打印字段
 		DisableCaller:       false,                                    // 禁用 caller 打印
 		DisableStacktrace:   false,                                    // 禁用 Stacktrace
 		EncoderConfig:       nil,
+		// 只记录这些 content type 的 body
+		BodyContentTypes: []string{"application/json", "text/*"},
 	}
 	app.Use(logit.NewGinLogger(conf))
 	app.POST("/ping", func(c *gin.Context) {
@@ -4,9 +4,9 @@ package logit
 
 import (
 	"context"
+	"sync"
 
 	"github.com/gin-gonic/gin"
-	jsoniter "github.com/json-iterator/go"
 	"github.com/rs/xid"
 	"go.uber.org/zap"
 )
@@ -19,8 +19,33 @@ const (
 	CtxLoggerName CtxKey = "ctx_logger"
 	// TraceIDKeyName define the trace id key name
 	TraceIDKeyName CtxKey = "trace_id"
+	// context 中保存请求级别累积字段的 key
+	ctxAccessFieldsKey CtxKey = "_log_access_fields_"
 )
 
+// accessFields 请求处理过程中累积的日志字段，可以在多个 goroutine 中并发添加
+type accessFields struct {
+	mu     sync.Mutex
+	fields []zap.Field
+}
+
+// add 添加字段
+func (a *accessFields) add(fields ...zap.Field) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.fields = append(a.fields, fields...)
+}
+
+// list 返回已添加字段的副本
+func (a *accessFields) list() []zap.Field {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.fields) == 0 {
+		return nil
+	}
+	return append([]zap.Field(nil), a.fields...)
+}
+
 // CtxLogger
 //
 //	@Description: get the ctxLogger in context
@@ -31,24 +56,92 @@ func CtxLogger(c context.Context, fields ...zap.Field) *zap.Logger {
 	if c == nil {
 		c = context.Background()
 	}
+	ctxLogger := storedCtxLogger(c)
+	if ctxLogger == nil {
+		_, ctxLogger = NewCtxLogger(c, CloneLogger(string(CtxLoggerName)), CtxTraceID(c))
+	}
+
+	// 添加 AddAccessFields 累积的字段
+	if accumulated := AccessFields(c); len(accumulated) > 0 {
+		ctxLogger = ctxLogger.With(accumulated...)
+	}
+	if len(fields) > 0 {
+		ctxLogger = ctxLogger.With(fields...)
+	}
+	return ctxLogger
+}
+
+// storedCtxLogger 获取 context 中保存的 ctxLogger ，没有时返回 nil
+func storedCtxLogger(c context.Context) *zap.Logger {
 	var ctxLoggerItf interface{}
 	if gc, ok := c.(*gin.Context); ok {
 		ctxLoggerItf, _ = gc.Get(string(CtxLoggerName))
 	} else {
 		ctxLoggerItf = c.Value(CtxLoggerName)
 	}
+	if ctxLoggerItf == nil {
+		return nil
+	}
+	return ctxLoggerItf.(*zap.Logger)
+}
 
-	var ctxLogger *zap.Logger
-	if ctxLoggerItf != nil {
-		ctxLogger = ctxLoggerItf.(*zap.Logger)
-	} else {
-		_, ctxLogger = NewCtxLogger(c, CloneLogger(string(CtxLoggerName)), CtxTraceID(c))
+// ctxAccessFields 获取 context 中的 accessFields ， gin.Context 从 c.Request 的 context 中获取
+func ctxAccessFields(c context.Context) *accessFields {
+	if c == nil {
+		return nil
+	}
+	if gc, ok := c.(*gin.Context); ok {
+		if gc.Request == nil {
+			return nil
+		}
+		c = gc.Request.Context()
 	}
+	acc, _ := c.Value(ctxAccessFieldsKey).(*accessFields)
+	return acc
+}
 
-	if len(fields) > 0 {
-		ctxLogger = ctxLogger.With(fields...)
+// withAccessFields 在 context 中创建新的 accessFields ， gin.Context 会替换 c.Request
+func withAccessFields(c context.Context) (context.Context, *accessFields) {
+	acc := &accessFields{}
+	if gc, ok := c.(*gin.Context); ok {
+		if gc.Request != nil {
+			gc.Request = gc.Request.WithContext(context.WithValue(gc.Request.Context(), ctxAccessFieldsKey, acc))
+		}
+		return gc, acc
 	}
-	return ctxLogger
+	return context.WithValue(c, ctxAccessFieldsKey, acc), acc
+}
+
+// AddAccessFields
+//
+//	@Description: 添加请求级别的日志字段，字段会记录到 GinLogger 的访问日志中，并附加到之后同一请求的 CtxLogger 日志中
+//	支持 gin.Context 和从 c.Request.Context() 派生的 context.Context ，可以在多个 goroutine 中并发调用
+//	context 中没有经过 GinLogger 时会创建新的 context 保存字段，需要使用返回的 context
+//	@param c
+//	@param fields
+//	@return context.Context
+func AddAccessFields(c context.Context, fields ...zap.Field) context.Context {
+	if c == nil {
+		c = context.Background()
+	}
+	acc := ctxAccessFields(c)
+	if acc == nil {
+		c, acc = withAccessFields(c)
+	}
+	acc.add(fields...)
+	return c
+}
+
+// AccessFields
+//
+//	@Description: 获取 AddAccessFields 添加的请求级别日志字段
+//	@param c
+//	@return []zap.Field
+func AccessFields(c context.Context) []zap.Field {
+	if acc := ctxAccessFields(c); acc != nil {
+		return acc.list()
+	}
+	return nil
 }
 
 func SetContextLogger(c context.Context, logger *zap.Logger) context.Context {
@@ -62,6 +155,8 @@ func SetContextLogger(c context.Context, logger *zap.Logger) context.Context {
 
 // CtxTraceID get trace id from context
 // Modify TraceIDPrefix change the prefix
+// gin.Context 只从 context keys 和 querystring 中获取，不读取请求 body ，
+// 需要从 json body 中获取时使用 GinLoggerConfig.TraceIDFromBody 或 GetGinTraceIDFromBody
 func CtxTraceID(c context.Context) string {
 	if c == nil {
 		c = context.Background()
@@ -74,10 +169,6 @@ func CtxTraceID(c context.Context) string {
 		if traceID := gc.Query(string(TraceIDKeyName)); traceID != "" {
 			return traceID
 		}
-		if traceID := jsoniter.Get(GetGinRequestBody(gc), string(TraceIDKeyName)).ToString(); traceID != "" {
-			return traceID
-		}
-
 	} else {
 		// get from go context
 		traceIDItf := c.Value(TraceIDKeyName)
@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"testing"
 
 	"github.com/gin-gonic/gin"
@@ -125,3 +126,52 @@ func TestCtxTraceID(t *testing.T) {
 		t.Fatal("context should return set value")
 	}
 }
+
+func TestAddAccessFields(t *testing.T) {
+	c := context.Background()
+	if fields := AccessFields(c); fields != nil {
+		t.Fatal("empty context should not have access fields", fields)
+	}
+	c = AddAccessFields(c, zap.String("user_id", "u1"))
+	c2 := AddAccessFields(c, zap.String("tenant", "t1"))
+	if c2 != c {
+		t.Error("context with access fields should be reused")
+	}
+	if fields := AccessFields(c); len(fields) != 2 {
+		t.Fatal("invalid access fields", fields)
+	}
+
+	gc, _ := gin.CreateTestContext(httptest.NewRecorder())
+	gc.Request, _ = http.NewRequest("GET", "/", nil)
+	AddAccessFields(gc, zap.String("order_id", "o1"))
+	if fields := AccessFields(gc.Request.Context()); len(fields) != 1 || fields[0].String != "o1" {
+		t.Error("gin context access fields should be saved in request context", fields)
+	}
+}
+
+func TestGinLoggerAccessFields(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	logfile := filepath.Join(t.TempDir(), "access.log")
+	app := gin.New()
+	app.Use(GinLoggerWithConfig(GinLoggerConfig{OutputPaths: []string{logfile}}))
+	app.GET("/orders", func(c *gin.Context) {
+		AddAccessFields(c, zap.String("user_id", "u1"))
+		// 从 c.Request.Context() 派生的 context 也可以添加字段
+		ctx, cancel := context.WithCancel(c.Request.Context())
+		defer cancel()
+		AddAccessFields(ctx, zap.String("order_id", "o1"))
+		CtxLogger(c).Info("handler log")
+		c.Status(200)
+	})
+	app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orders", nil))
+
+	logs := readTestLogs(t, logfile)
+	if len(logs) != 2 {
+		t.Fatal("invalid logs count", len(logs))
+	}
+	for _, l := range logs {
+		if l["user_id"] != "u1" || l["order_id"] != "o1" {
+			t.Error("access fields should be logged", l)
+		}
+	}
+}
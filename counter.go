@@ -0,0 +1,281 @@
+package logit
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// 默认统计的 msg 种类上限
+	defaultLevelCounterMaxMessages = 1000
+	// 超出 msg 种类上限后使用的 msg 标签
+	levelCounterOtherMessage = "other"
+)
+
+// LevelCounterConfig LevelCounter 支持的配置项字段定义
+type LevelCounterConfig struct {
+	// 指标名，输出为 <Name>{logger="",level=""} 格式
+	// Optional. Default value is logit_log_entries_total
+	Name string
+	// 需要统计的日志级别，通过 AttachCore 使用时生效
+	// Optional. 默认统计全部级别
+	Level zapcore.LevelEnabler
+	// 是否按日志 msg 分别统计，适用于 msg 是固定模板、变量放在字段中的日志， Infof 这类格式化后的 msg 会导致计数分散
+	// Optional.
+	EnableMessage bool
+	// 按 msg 统计时最多记录的 msg 种类，超出后计入 msg 为 other 的计数
+	// Optional. Default value is 1000
+	MaxMessages int
+}
+
+// LevelCount 单个 logger 、日志级别（和 msg ）的日志条数
+type LevelCount struct {
+	Logger  string `json:"logger"`
+	Level   string `json:"level"`
+	Message string `json:"message,omitempty"`
+	Count   uint64 `json:"count"`
+}
+
+// levelCounterKey 计数的索引
+type levelCounterKey struct {
+	logger  string
+	level   zapcore.Level
+	message string
+}
+
+// LevelCounter 按 logger name 和日志级别统计日志条数，可以用于统计每分钟 error/warn 日志数量
+// 通过 AttachCore(logger, counter.Core()) 或 Options.LevelCounter 使用，通过 Handler 以 Prometheus 文本格式输出
+type LevelCounter struct {
+	name          string
+	level         zapcore.LevelEnabler
+	enableMessage bool
+	maxMessages   int
+
+	mu       sync.Mutex
+	counts   map[levelCounterKey]uint64
+	messages map[string]struct{}
+}
+
+// NewLevelCounter
+//
+//	@Description: 创建日志条数计数器
+//	@param conf
+//	@return *LevelCounter
+func NewLevelCounter(conf LevelCounterConfig) *LevelCounter {
+	if conf.Name == "" {
+		conf.Name = "logit_log_entries_total"
+	}
+	if conf.Level == nil {
+		conf.Level = zapcore.DebugLevel
+	}
+	if conf.MaxMessages <= 0 {
+		conf.MaxMessages = defaultLevelCounterMaxMessages
+	}
+	return &LevelCounter{
+		name:          conf.Name,
+		level:         conf.Level,
+		enableMessage: conf.EnableMessage,
+		maxMessages:   conf.MaxMessages,
+		counts:        map[levelCounterKey]uint64{},
+		messages:      map[string]struct{}{},
+	}
+}
+
+// Core
+//
+//	@Description: 返回只计数不输出的 zapcore.Core ，通过 AttachCore 添加到 logger
+//	@receiver lc
+//	@return zapcore.Core
+func (lc *LevelCounter) Core() zapcore.Core {
+	return &levelCounterCore{LevelEnabler: lc.level, counter: lc}
+}
+
+// inc
+//
+//	@Description: 记录一条日志
+//	@receiver lc
+//	@param ent
+func (lc *LevelCounter) inc(ent zapcore.Entry) {
+	key := levelCounterKey{logger: ent.LoggerName, level: ent.Level}
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	if lc.enableMessage {
+		key.message = ent.Message
+		if _, exists := lc.messages[key.message]; !exists {
+			if len(lc.messages) >= lc.maxMessages {
+				key.message = levelCounterOtherMessage
+			} else {
+				lc.messages[key.message] = struct{}{}
+			}
+		}
+	}
+	lc.counts[key]++
+}
+
+// Count
+//
+//	@Description: 获取 logger 在日志级别下的日志条数，按 msg 统计时返回全部 msg 的合计
+//	@receiver lc
+//	@param logger logger name
+//	@param level
+//	@return uint64
+func (lc *LevelCounter) Count(logger string, level zapcore.Level) uint64 {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	var total uint64
+	for key, n := range lc.counts {
+		if key.logger == logger && key.level == level {
+			total += n
+		}
+	}
+	return total
+}
+
+// CountMessage
+//
+//	@Description: 获取 logger 在日志级别下指定 msg 的日志条数，需要开启 EnableMessage
+//	@receiver lc
+//	@param logger logger name
+//	@param level
+//	@param message
+//	@return uint64
+func (lc *LevelCounter) CountMessage(logger string, level zapcore.Level, message string) uint64 {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	return lc.counts[levelCounterKey{logger: logger, level: level, message: message}]
+}
+
+// Snapshot
+//
+//	@Description: 获取全部计数，按 logger 、级别、 msg 排序
+//	@receiver lc
+//	@return []LevelCount
+func (lc *LevelCounter) Snapshot() []LevelCount {
+	lc.mu.Lock()
+	keys := make([]levelCounterKey, 0, len(lc.counts))
+	for key := range lc.counts {
+		keys = append(keys, key)
+	}
+	counts := make([]LevelCount, 0, len(keys))
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].logger != keys[j].logger {
+			return keys[i].logger < keys[j].logger
+		}
+		if keys[i].level != keys[j].level {
+			return keys[i].level < keys[j].level
+		}
+		return keys[i].message < keys[j].message
+	})
+	for _, key := range keys {
+		counts = append(counts, LevelCount{
+			Logger:  key.logger,
+			Level:   key.level.String(),
+			Message: key.message,
+			Count:   lc.counts[key],
+		})
+	}
+	lc.mu.Unlock()
+	return counts
+}
+
+// Reset
+//
+//	@Description: 清空全部计数
+//	@receiver lc
+func (lc *LevelCounter) Reset() {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	lc.counts = map[levelCounterKey]uint64{}
+	lc.messages = map[string]struct{}{}
+}
+
+// WriteTo
+//
+//	@Description: 以 Prometheus 文本格式输出全部计数
+//	@receiver lc
+//	@param w
+//	@return int64
+//	@return error
+func (lc *LevelCounter) WriteTo(w io.Writer) (int64, error) {
+	cw := &countWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	bw.WriteString("# HELP " + lc.name + " Total number of log entries by logger and level.\n")
+	bw.WriteString("# TYPE " + lc.name + " counter\n")
+	for _, count := range lc.Snapshot() {
+		labels := `logger="` + metricsLabelValue(count.Logger) + `",level="` + count.Level + `"`
+		if lc.enableMessage {
+			labels += `,message="` + metricsLabelValue(count.Message) + `"`
+		}
+		bw.WriteString(lc.name + "{" + labels + "} " + strconv.FormatUint(count.Count, 10) + "\n")
+	}
+	err := bw.Flush()
+	return cw.n, err
+}
+
+// ServeHTTP
+//
+//	@Description: 实现 http.Handler ，以 Prometheus 文本格式输出计数
+//	@receiver lc
+//	@param w
+//	@param r
+func (lc *LevelCounter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", metricsContentType)
+	_, _ = lc.WriteTo(w)
+}
+
+// levelCounterCore 只计数不输出的 zapcore.Core
+type levelCounterCore struct {
+	zapcore.LevelEnabler
+	counter *LevelCounter
+}
+
+// With
+//
+//	@Description: 计数与字段无关，返回自身
+//	@receiver c
+//	@param fields
+//	@return zapcore.Core
+func (c *levelCounterCore) With([]zapcore.Field) zapcore.Core {
+	return c
+}
+
+// Check
+//
+//	@Description: 日志级别需要统计时添加到 CheckedEntry
+//	@receiver c
+//	@param ent
+//	@param ce
+//	@return *zapcore.CheckedEntry
+func (c *levelCounterCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write
+//
+//	@Description: 记录日志条数
+//	@receiver c
+//	@param ent
+//	@param fields
+//	@return error
+func (c *levelCounterCore) Write(ent zapcore.Entry, _ []zapcore.Field) error {
+	c.counter.inc(ent)
+	return nil
+}
+
+// Sync
+//
+//	@Description: 无需 sync
+//	@receiver c
+//	@return error
+func (c *levelCounterCore) Sync() error {
+	return nil
+}
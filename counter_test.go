@@ -0,0 +1,95 @@
+package logit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLevelCounterOptions(t *testing.T) {
+	counter := NewLevelCounter(LevelCounterConfig{})
+	logger, err := NewLogger(Options{
+		Level:        "debug",
+		OutputPaths:  []string{filepath.Join(t.TempDir(), "counter.log")},
+		LevelCounter: counter,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Named("order").Error("create failed")
+	logger.Named("order").Error("pay failed")
+	logger.Named("order").Warn("slow")
+	logger.Info("started")
+
+	if n := counter.Count("logit.order", zap.ErrorLevel); n != 2 {
+		t.Error("invalid error count", n)
+	}
+	if n := counter.Count("logit.order", zap.WarnLevel); n != 1 {
+		t.Error("invalid warn count", n)
+	}
+	if n := counter.Count("logit", zap.InfoLevel); n != 1 {
+		t.Error("invalid info count", n)
+	}
+	snapshot := counter.Snapshot()
+	if len(snapshot) != 3 || snapshot[0] != (LevelCount{Logger: "logit", Level: "info", Count: 1}) {
+		t.Error("invalid snapshot", snapshot)
+	}
+
+	w := httptest.NewRecorder()
+	counter.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, line := range []string{
+		"# TYPE logit_log_entries_total counter",
+		`logit_log_entries_total{logger="logit.order",level="error"} 2`,
+		`logit_log_entries_total{logger="logit.order",level="warn"} 1`,
+	} {
+		if !strings.Contains(w.Body.String(), line+"\n") {
+			t.Errorf("metrics missing %q\n%s", line, w.Body.String())
+		}
+	}
+
+	counter.Reset()
+	if len(counter.Snapshot()) != 0 {
+		t.Error("reset failed")
+	}
+}
+
+func TestLevelCounterAttachCore(t *testing.T) {
+	counter := NewLevelCounter(LevelCounterConfig{
+		Name:          "app_logs_total",
+		Level:         zapcore.WarnLevel,
+		EnableMessage: true,
+		MaxMessages:   2,
+	})
+	logger := AttachCore(zap.NewNop().Named("svc"), counter.Core())
+	logger.Debug("ignored")
+	logger.With(zap.Int("id", 1)).Warn("retry")
+	logger.Warn("retry")
+	logger.Error("failed")
+	logger.Error("another failure")
+
+	if n := counter.CountMessage("svc", zap.WarnLevel, "retry"); n != 2 {
+		t.Error("invalid retry count", n)
+	}
+	if n := counter.CountMessage("svc", zap.ErrorLevel, "other"); n != 1 {
+		t.Error("messages over MaxMessages should be counted as other", n)
+	}
+	if n := counter.Count("svc", zap.ErrorLevel); n != 2 {
+		t.Error("invalid error count", n)
+	}
+	if n := counter.Count("svc", zap.DebugLevel); n != 0 {
+		t.Error("debug level should not be counted", n)
+	}
+
+	var buf strings.Builder
+	if _, err := counter.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `app_logs_total{logger="svc",level="warn",message="retry"} 2`+"\n") {
+		t.Error("invalid metrics", buf.String())
+	}
+}
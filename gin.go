@@ -2,6 +2,7 @@ package logit
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -10,6 +11,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	jsoniter "github.com/json-iterator/go"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -19,6 +21,10 @@ const (
 	defaultGinLoggerName = "access"
 	// 默认慢请求时间 3s
 	defaultGinSlowThreshold = time.Second * 3
+	// request context 中保存请求 body 记录方法的 key
+	ctxGinRequestBodyKey CtxKey = "_log_gin_request_body_"
+	// request context 中保存 GinLogger 解析出的客户端 IP 的 key
+	ctxGinClientIPKey CtxKey = "_log_gin_client_ip_"
 )
 
 //
@@ -51,11 +57,50 @@ func GetGinTraceIDFromPostForm(c *gin.Context) string {
 	return c.PostForm(string(TraceIDKeyName))
 }
 
+//
+// GetGinTraceIDFromBody
+//  @Description: 从 gin 的 json 请求 body 的前 limit 个字节中获取 key 为 TraceIDKeyName 的值作为 traceid
+//  读取的内容会在 handler 读取 body 时重新返回，不会读取超过 limit 的内容
+//  @param c
+//  @param limit
+//  @return string
+//
+func GetGinTraceIDFromBody(c *gin.Context, limit int) string {
+	if !matchContentType(c.ContentType(), []string{"application/json"}) {
+		return ""
+	}
+	body := peekGinRequestBody(c, limit)
+	if len(body) == 0 {
+		return ""
+	}
+	return jsoniter.Get(body, string(TraceIDKeyName)).ToString()
+}
+
 // GinLogExtends gin 日志中间件记录的扩展
+// 每个请求单独生成，请求处理完成后计算出全部字段再以值传递给 Formatter
 type GinLogExtends struct {
 	// 请求处理耗时 (秒)
-	Latency    float64 `json:"latency_seconds"`
-	HandleName string  `json:"handle_name"`
+	Latency float64 `json:"latency_seconds"`
+	// 从请求开始到第一次写入响应 body 的耗时 (秒)，未写入 body 时为 0
+	FirstByteLatency float64 `json:"first_byte_latency_seconds"`
+	HandleName       string  `json:"handle_name"`
+	// 请求开始时间
+	ReqTime time.Time `json:"req_time"`
+	// 请求的 trace id
+	TraceID string `json:"trace_id"`
+	// 客户端 IP ，配置了 TrustedProxies 时为从代理请求头中解析出的 IP
+	ClientIP   string `json:"client_ip"`
+	Method     string `json:"method"`
+	Path       string `json:"path"`
+	Host       string `json:"host"`
+	RequestURI string `json:"request_uri"`
+	Proto      string `json:"proto"`
+	// 响应状态码
+	StatusCode int `json:"status_code"`
+	// 响应 body 字节数，未写入时为 -1
+	BodySize int `json:"body_size"`
+	// 是否为慢请求
+	Slow bool `json:"slow"`
 }
 
 // GinLoggerConfig GinLogger 支持的配置项字段定义
@@ -63,6 +108,11 @@ type GinLoggerConfig struct {
 	Name string
 	// Optional. Default value is logit.defaultGinLogFormatter
 	Formatter func(*gin.Context, GinLogExtends) string
+	// 访问日志 msg 的格式，Formatter 为空时生效
+	// 可以是预置格式 pipe 、 common 、 combined 、 elb 、 ecs ，也可以是 CompileGinLogTemplate 支持的模板，
+	// 如 `%h %l %u %t "%r" %>s %b %D {trace_id}`
+	// Optional. Default value is pipe
+	LogFormat string
 	// SkipPaths is a url path array which logs are not written.
 	// Optional.
 	SkipPaths []string
@@ -71,6 +121,9 @@ type GinLoggerConfig struct {
 	// TraceIDFunc 获取或生成 trace id 的函数
 	// Optional.
 	TraceIDFunc func(*gin.Context) string
+	// 未设置 TraceIDFunc 时，是否从 json 请求 body 中获取 trace id ，最多读取 RequestBodyMaxSize 个字节，不限制时最多读取 4KB
+	// Optional.
+	TraceIDFromBody bool
 	// 是否使用详细模式打印日志，记录更多字段信息
 	// Optional.
 	EnableDetails bool
@@ -90,8 +143,41 @@ type GinLoggerConfig struct {
 	// 是否打印响应体信息
 	// Optional.
 	EnableResponseBody bool
+	// 请求体最多记录的字节数，超出部分截断并追加截断标记，默认 4KB ，小于 0 表示不限制
+	// Optional.
+	RequestBodyMaxSize int
+	// 响应体最多记录的字节数，超出部分截断并追加截断标记，默认 4KB ，小于 0 表示不限制
+	// Optional.
+	ResponseBodyMaxSize int
+	// 记录 body 的 content type 白名单，支持 text/* 形式的通配
+	// 默认只记录 json 、 xml 、 form 和 text 类型的 body
+	// Optional.
+	BodyContentTypes []string
+	// 是否将白名单之外的 body （如图片、 octet-stream ）以 base64 编码后记录，默认不记录
+	// Optional.
+	EnableBinaryBodyBase64 bool
 	// 慢请求时间阈值 请求处理时间超过该值则使用 Warn 级别打印日志
 	SlowThreshold time.Duration
+	// 可信代理的 IP 或 CIDR ，设置后只有直连地址是可信代理时才从 Forwarded 、 X-Forwarded-For 、 X-Real-IP 中解析客户端 IP
+	// Optional. 未设置时使用 c.ClientIP()
+	TrustedProxies []string
+	// 是否解析 User-Agent ，记录 ua_browser 、 ua_browser_version 、 ua_os 、 ua_device 字段
+	// Optional.
+	EnableUserAgentFields bool
+	// 根据客户端 IP 查询国家和 ASN 信息，记录 geo_country 、 geo_asn 、 geo_as_org 字段
+	// 可以使用 OpenMaxMindGeoIP 打开本地 MaxMind 数据库，由调用方在不再使用时 Close
+	// Optional.
+	GeoIP GeoIPReader
+	// 按请求方法和路由模板覆盖慢请求阈值、 header/body 记录开关、成功请求的日志级别和采样率
+	// Optional.
+	RouteRules []GinRouteRule
+	// 成功请求的采样配置，失败请求和慢请求始终记录，为 nil 时记录全部请求
+	// Optional.
+	Sampling *GinSamplingConfig
+	// 按路由模板、请求方法和状态码分类统计请求数和耗时直方图，通过 GinMetrics.Handler 输出
+	// SkipPaths 中的请求不统计
+	// Optional.
+	Metrics *GinMetrics
 	// 日志输出路径，默认 []string{"console"}
 	// Optional.
 	OutputPaths []string
@@ -127,11 +213,11 @@ func GinLogger() gin.HandlerFunc {
 //
 func defaultGinLogFormatter(c *gin.Context, ext GinLogExtends) string {
 	msg := fmt.Sprintf("%s|%s|%s%s|%d|%f",
-		c.ClientIP(),
-		c.Request.Method,
-		c.Request.Host,
-		c.Request.RequestURI,
-		c.Writer.Status(),
+		ext.ClientIP,
+		ext.Method,
+		ext.Host,
+		ext.RequestURI,
+		ext.StatusCode,
 		ext.Latency,
 	)
 	return msg
@@ -144,6 +230,17 @@ func defaultGinLogFormatter(c *gin.Context, ext GinLogExtends) string {
 //  @return traceID
 //
 func defaultGinTraceIDFunc(c *gin.Context) (traceID string) {
+	return ginTraceID(c, 0)
+}
+
+//
+// ginTraceID
+//  @Description: 依次从 header 、 post form 、 querystring 和 json 请求 body 中获取 traceID ，都没有时生成新的 traceID
+//  @param c
+//  @param bodyLimit 从请求 body 中获取时最多读取的字节数，小于等于 0 时不从请求 body 中获取
+//  @return traceID
+//
+func ginTraceID(c *gin.Context, bodyLimit int) (traceID string) {
 	traceID = GetGinTraceIDFromHeader(c)
 	if traceID != "" {
 		return
@@ -156,6 +253,12 @@ func defaultGinTraceIDFunc(c *gin.Context) (traceID string) {
 	if traceID != "" {
 		return
 	}
+	if bodyLimit > 0 {
+		traceID = GetGinTraceIDFromBody(c, bodyLimit)
+		if traceID != "" {
+			return
+		}
+	}
 	traceID = CtxTraceID(c)
 	return
 }
@@ -180,14 +283,9 @@ func NewGinLogger(conf GinLoggerConfig) gin.HandlerFunc {
 //  @return gin.HandlerFunc
 //
 func GinLoggerWithConfig(conf GinLoggerConfig) gin.HandlerFunc {
-	formatter := conf.Formatter
-	if formatter == nil {
-		formatter = defaultGinLogFormatter
-	}
+	logFormat := newGinLogFormat(conf)
+	formatter := logFormat.formatter
 	getTraceID := conf.TraceIDFunc
-	if getTraceID == nil {
-		getTraceID = defaultGinTraceIDFunc
-	}
 
 	var skipRegexps []*regexp.Regexp
 	for _, p := range conf.SkipPathRegexps {
@@ -200,6 +298,29 @@ func GinLoggerWithConfig(conf GinLoggerConfig) gin.HandlerFunc {
 	if conf.SlowThreshold.Seconds() <= 0 {
 		conf.SlowThreshold = defaultGinSlowThreshold
 	}
+	if conf.RequestBodyMaxSize == 0 {
+		conf.RequestBodyMaxSize = defaultGinBodyMaxSize
+	}
+	if conf.ResponseBodyMaxSize == 0 {
+		conf.ResponseBodyMaxSize = defaultGinBodyMaxSize
+	}
+	if getTraceID == nil && conf.TraceIDFromBody {
+		traceIDBodyLimit := conf.RequestBodyMaxSize
+		if traceIDBodyLimit < 0 {
+			traceIDBodyLimit = defaultGinBodyMaxSize
+		}
+		getTraceID = func(c *gin.Context) string {
+			return ginTraceID(c, traceIDBodyLimit)
+		}
+	}
+	if getTraceID == nil {
+		getTraceID = defaultGinTraceIDFunc
+	}
+	if len(conf.BodyContentTypes) == 0 {
+		conf.BodyContentTypes = defaultGinBodyContentTypes
+	}
+	routeRules := newGinRouteRules(conf.RouteRules)
+	enricher := newGinEnricher(conf)
 	ginLogger, err := NewLogger(Options{
 		Level:             "debug",
 		Format:            "json",
@@ -212,8 +333,15 @@ func GinLoggerWithConfig(conf GinLoggerConfig) gin.HandlerFunc {
 	if err != nil {
 		panic("new gin error failed: " + err.Error())
 	}
-
-	var ginLogExtends = GinLogExtends{}
+	// 如果 logger name 为空
+	if conf.Name == "" {
+		conf.Name = defaultGinLoggerName
+	}
+	// 采样汇总日志不属于某个请求，不带 trace id ，由采样器的定时器按间隔输出
+	summaryLogger := ginLogger.Named(conf.Name)
+	sampler := newGinSampler(conf.Sampling, func(fields []zap.Field) {
+		summaryLogger.Info("access log sampling summary", fields...)
+	})
 
 	return func(c *gin.Context) {
 		if skipLog(c.Request.URL.Path, conf.SkipPaths, skipRegexps) {
@@ -221,6 +349,17 @@ func GinLoggerWithConfig(conf GinLoggerConfig) gin.HandlerFunc {
 			return
 		}
 		start := time.Now()
+		// 当前请求生效的日志配置
+		policy := routeRules.policy(conf, c.Request.Method, c.FullPath())
+
+		// 旁路记录 handler 读取的请求 body ，不在白名单中且未开启 base64 时不保存内容
+		// 需要在获取 trace id 之前替换，之后对 body 的读取都经过记录和长度限制
+		requestContentType := c.ContentType()
+		requestBodyLimit := conf.RequestBodyMaxSize
+		if !conf.EnableBinaryBodyBase64 && !matchContentType(requestContentType, conf.BodyContentTypes) {
+			requestBodyLimit = 0
+		}
+		reqBodyReader := teeGinRequestBody(c, requestBodyLimit)
 
 		traceID := getTraceID(c)
 		// 设置 trace id 到 request header 中
@@ -230,45 +369,90 @@ func GinLoggerWithConfig(conf GinLoggerConfig) gin.HandlerFunc {
 		// 设置 trace id 和 ctxLogger 到 context 中
 		_, ctxLogger := NewCtxLogger(c, ginLogger, traceID)
 		_, shortHandlerName := path.Split(c.HandlerName())
-		ginLogExtends.HandleName = shortHandlerName
-		// 如果 logger name 为空
-		if conf.Name == "" {
-			conf.Name = defaultGinLoggerName
+		clientIP := enricher.clientIP(c)
+		// 请求级别的日志扩展，只在当前请求中使用
+		ext := GinLogExtends{
+			HandleName: shortHandlerName,
+			ReqTime:    start,
+			TraceID:    traceID,
+			ClientIP:   clientIP,
+			Method:     c.Request.Method,
+			Path:       c.Request.URL.Path,
+			Host:       c.Request.Host,
+			RequestURI: c.Request.RequestURI,
+			Proto:      c.Request.Proto,
 		}
 		// 创建基础 logger，可以记录基础的信息
 		accessLogger := ctxLogger.Named(conf.Name).With(
 			zap.Time("req_time", start),
-			zap.String("client_ip", c.ClientIP()),
+			zap.String("client_ip", clientIP),
 			zap.String("method", c.Request.Method),
 			zap.String("path", c.Request.URL.Path),
 			zap.String("host", c.Request.Host),
 			zap.String("handle", shortHandlerName),
-		)
+		).With(enricher.fields(c, clientIP)...)
 		// 判断是否打印请求 header
-		if conf.EnableRequestHeader {
+		if policy.enableRequestHeader {
 			accessLogger = accessLogger.With(zap.Any("request_header", c.Request.Header))
 		}
 		// 判断是否打印请求 form
 		if conf.EnableRequestForm {
 			accessLogger = accessLogger.With(zap.Any("request_form", c.Request.Form))
 		}
-		// 判断是否打印请求 body
-		if conf.EnableRequestBody {
-			accessLogger = accessLogger.With(zap.Any("request_body", string(GetGinRequestBody(c))))
+		// drain 为 false 时只记录 handler 已读取的 body ，不再读取连接
+		requestBodyFields := func(drain bool) []zap.Field {
+			if reqBodyReader == nil {
+				return nil
+			}
+			if drain {
+				reqBodyReader.drain()
+			}
+			return ginBodyFields("request_body", reqBodyReader.body, requestContentType, conf.BodyContentTypes, conf.EnableBinaryBodyBase64)
 		}
-		rspBodyWriter := &responseBodyWriter{body: bytes.NewBufferString(""), ResponseWriter: c.Writer}
-		if conf.EnableResponseBody {
-			// 开启记录响应 body 时，保存 body 到 rspBodyWriter.body 中
-			c.Writer = rspBodyWriter
+		// 保存到 request context 中，供 GinRecovery 等中间件获取已记录的请求 body 和客户端 IP
+		reqCtx := context.WithValue(c.Request.Context(), ctxGinRequestBodyKey, requestBodyFields)
+		c.Request = c.Request.WithContext(context.WithValue(reqCtx, ctxGinClientIPKey, clientIP))
+		// 创建请求级别的字段累积器， handler 中使用 AddAccessFields 添加的字段会记录到访问日志中
+		_, accessFieldsAcc := withAccessFields(c)
+		// 创建请求级别的 sql 统计，使用请求 context 执行 sql 时由 GormLogger 记录
+		_, queryStats := WithQueryStats(c)
+		// 使用 rspRecorder 记录首字节耗时和写入次数，开启记录响应 body 时，保存 body 到 rspRecorder.body 中
+		var rspBody *bodyBuffer
+		if policy.enableResponseBody {
+			rspBody = newBodyBuffer(conf.ResponseBodyMaxSize)
+		}
+		rspRecorder := newGinResponseRecorder(c.Writer, start, rspBody)
+		c.Writer = rspRecorder
+		responseBodyFields := func() []zap.Field {
+			// 流式响应只记录写入次数
+			if rspRecorder.Streaming() {
+				return nil
+			}
+			return ginBodyFields("response_body", rspRecorder.body, c.Writer.Header().Get("Content-Type"), conf.BodyContentTypes, conf.EnableBinaryBodyBase64)
 		}
 
 		defer func() {
-			ginLogExtends.Latency = time.Since(start).Seconds()
-			// 记录 status code 和 latency
+			ext.Latency = time.Since(start).Seconds()
+			ext.FirstByteLatency = rspRecorder.FirstByteLatency().Seconds()
+			ext.StatusCode = c.Writer.Status()
+			ext.BodySize = c.Writer.Size()
+			ext.Slow = ext.Latency > policy.slowThreshold.Seconds()
+			// 指标不受采样影响，记录全部请求
+			if conf.Metrics != nil {
+				conf.Metrics.observe(c.FullPath(), ext.Method, ext.StatusCode, ext.Latency)
+			}
+			// 记录 status code 、 latency 和首字节 latency
 			accessLogger = accessLogger.With(
-				zap.Int("status_code", c.Writer.Status()),
-				zap.Float64("latency_seconds", ginLogExtends.Latency),
+				zap.Int("status_code", ext.StatusCode),
+				zap.Float64("latency_seconds", ext.Latency),
+				zap.Float64("first_byte_latency_seconds", ext.FirstByteLatency),
 			)
+			// 流式响应记录写入次数
+			accessLogger = accessLogger.With(rspRecorder.streamingFields()...)
+			// handler 中使用 AddAccessFields 添加的字段
+			accessLogger = accessLogger.With(accessFieldsAcc.list()...)
+			// 请求中 sql 的执行次数和总耗时
+			accessLogger = accessLogger.With(queryStats.fields()...)
 			// handler 中使用 c.Error(err) 后，会打印到 context_errors 字段中
 			if len(c.Errors) > 0 {
 				accessLogger = accessLogger.With(zap.String("context_errors", c.Errors.String()))
@@ -277,25 +461,25 @@ func GinLoggerWithConfig(conf GinLoggerConfig) gin.HandlerFunc {
 			if conf.EnableContextKeys {
 				accessLogger = accessLogger.With(zap.Any("context_keys", c.Keys))
 			}
-			// 判断是否打印响应 body
-			if conf.EnableResponseBody {
-				accessLogger = accessLogger.With(zap.Any("response_body", rspBodyWriter.body.String()))
+			// 判断是否打印请求 body
+			if policy.enableRequestBody {
+				accessLogger = accessLogger.With(requestBodyFields(true)...)
 			}
-			detailFields := []zap.Field{
-				zap.String("query", c.Request.URL.RawQuery),
-				zap.String("proto", c.Request.Proto),
-				zap.Int("content_length", int(c.Request.ContentLength)),
-				zap.String("remote_addr", c.Request.RemoteAddr),
-				zap.String("request_uri", c.Request.RequestURI),
-				zap.String("referer", c.Request.Referer()),
-				zap.String("user_agent", c.Request.UserAgent()),
-				zap.String("content_type", c.ContentType()),
-				zap.Int("body_size", c.Writer.Size()),
+			// 判断是否打印响应 body
+			if policy.enableResponseBody {
+				accessLogger = accessLogger.With(responseBodyFields()...)
 			}
+			detailFields := ginDetailFields(c)
 			if conf.EnableDetails {
 				accessLogger = accessLogger.With(detailFields...)
 			}
-			log := accessLogger.Info
+			// 日志格式附带的字段，如 ecs 格式的字段
+			if logFormat.fields != nil {
+				accessLogger = accessLogger.With(logFormat.fields(c, ext)...)
+			}
+			// 成功请求默认使用 info 级别，可以通过路由规则修改
+			level := policy.successLevel
+			success := true
 			// 打印访问日志，根据状态码确定日志打印级别
 			if c.Writer.Status() >= http.StatusInternalServerError || len(c.Errors) > 0 {
 				// 500+ 始终打印带 details 的 error 级别日志
@@ -304,22 +488,29 @@ func GinLoggerWithConfig(conf GinLoggerConfig) gin.HandlerFunc {
 				accessLogger = accessLogger.With(zap.Any("context_keys", c.Keys))
 				accessLogger = accessLogger.With(zap.Any("request_header", c.Request.Header))
 				accessLogger = accessLogger.With(zap.Any("request_form", c.Request.Form))
-				accessLogger = accessLogger.With(zap.String("request_body", string(GetGinRequestBody(c))))
-				accessLogger = accessLogger.With(zap.String("response_body", rspBodyWriter.body.String()))
-				log = accessLogger.Error
+				if !policy.enableRequestBody {
+					accessLogger = accessLogger.With(requestBodyFields(true)...)
+				}
+				if !policy.enableResponseBody {
+					accessLogger = accessLogger.With(responseBodyFields()...)
+				}
+				level = zap.ErrorLevel
+				success = false
 			} else if c.Writer.Status() >= http.StatusBadRequest {
 				// 400+ 默认使用 warn 级别
-				log = accessLogger.Warn
+				level = zap.WarnLevel
+				success = false
 			}
 
 			// 慢请求使用 Warn 记录
-			if ginLogExtends.Latency > conf.SlowThreshold.Seconds() {
+			if ext.Slow {
 				accessLogger.Warn(
-					formatter(c, ginLogExtends)+" hit slow request.",
-					zap.Float64("slow_threshold", conf.SlowThreshold.Seconds()),
+					formatter(c, ext)+" hit slow request.",
+					zap.Float64("slow_threshold", policy.slowThreshold.Seconds()),
 				)
-			} else {
-				log(formatter(c, ginLogExtends))
+			} else if !success || sampler.sample(c.Request.Method+" "+c.FullPath(), policy, time.Now()) {
+				// 成功请求按采样配置记录
+				accessLogger.Log(level, formatter(c, ext))
 			}
 		}()
 
@@ -327,6 +518,53 @@ func GinLoggerWithConfig(conf GinLoggerConfig) gin.HandlerFunc {
 	}
 }
 
+//
+// ginDetailFields
+//  @Description: 详细模式下记录的请求信息字段
+//  @param c
+//  @return []zap.Field
+//
+func ginDetailFields(c *gin.Context) []zap.Field {
+	return []zap.Field{
+		zap.String("query", c.Request.URL.RawQuery),
+		zap.String("proto", c.Request.Proto),
+		zap.Int("content_length", int(c.Request.ContentLength)),
+		zap.String("remote_addr", c.Request.RemoteAddr),
+		zap.String("request_uri", c.Request.RequestURI),
+		zap.String("referer", c.Request.Referer()),
+		zap.String("user_agent", c.Request.UserAgent()),
+		zap.String("content_type", c.ContentType()),
+		zap.Int("body_size", c.Writer.Size()),
+	}
+}
+
+//
+// ginClientIP
+//  @Description: 获取 GinLogger 中间件解析出的客户端 IP ，与访问日志中的 client_ip 相同，未使用 GinLogger 时返回 c.ClientIP()
+//  @param c
+//  @return string
+//
+func ginClientIP(c *gin.Context) string {
+	if clientIP, ok := c.Request.Context().Value(ctxGinClientIPKey).(string); ok {
+		return clientIP
+	}
+	return c.ClientIP()
+}
+
+//
+// ginRequestBodyFields
+//  @Description: 获取 GinLogger 中间件旁路记录的请求 body 字段，只包含 handler 已读取的部分，不会读取剩余的 body
+//  未使用 GinLogger 时返回 nil
+//  @param c
+//  @return []zap.Field
+//
+func ginRequestBodyFields(c *gin.Context) []zap.Field {
+	if fields, ok := c.Request.Context().Value(ctxGinRequestBodyKey).(func(bool) []zap.Field); ok {
+		return fields(false)
+	}
+	return nil
+}
+
 //
 // skipLog
 //  @Description: 判断是否需要跳过日志记录
@@ -352,7 +590,7 @@ func skipLog(path string, SkipPaths []string, skipRegexps []*regexp.Regexp) bool
 
 //
 // GetGinRequestBody
-//  @Description: 获取请求 body
+//  @Description: 获取请求 body ，会读取整个 body 到内存中，请求 body 可能很大时不要使用
 //  @param c
 //  @return []byte
 //
@@ -371,22 +609,3 @@ func GetGinRequestBody(c *gin.Context) []byte {
 	}
 	return requestBody
 }
-
-// 用于记录响应 body
-type responseBodyWriter struct {
-	gin.ResponseWriter
-	body *bytes.Buffer
-}
-
-//
-// Write
-//  @Description: 覆盖 ResponseWriter 接口的 Write 方法，将 body 保存到 responseBodyWriter.body 中
-//  @receiver w
-//  @param b
-//  @return int
-//  @return error
-//
-func (w responseBodyWriter) Write(b []byte) (int, error) {
-	w.body.Write(b)
-	return w.ResponseWriter.Write(b)
-}
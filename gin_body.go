@@ -0,0 +1,229 @@
+package logit
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	// 默认请求体、响应体最多记录 4KB
+	defaultGinBodyMaxSize = 4 << 10
+	// body 超出最大记录长度被截断时追加的标记
+	ginBodyTruncatedMarker = "...(truncated)"
+	// 不限制记录长度时，请求处理完成后最多补读的请求 body 字节数
+	ginBodyDrainMaxSize = 1 << 20
+)
+
+// defaultGinBodyContentTypes 默认记录 body 的 content type 白名单
+// multipart 、 octet-stream 、图片等二进制内容默认不记录
+var defaultGinBodyContentTypes = []string{
+	"application/json",
+	"application/xml",
+	"application/x-www-form-urlencoded",
+	"text/*",
+}
+
+// bodyBuffer 只保存前 limit 个字节的 buffer ，超出部分丢弃并标记为截断
+type bodyBuffer struct {
+	buf bytes.Buffer
+	// 最大保存长度，小于 0 表示不限制
+	limit     int
+	truncated bool
+}
+
+// newBodyBuffer
+//
+//	@Description: 创建最多保存 limit 个字节的 bodyBuffer
+//	@param limit 小于 0 表示不限制
+//	@return *bodyBuffer
+func newBodyBuffer(limit int) *bodyBuffer {
+	return &bodyBuffer{limit: limit}
+}
+
+// Write
+//
+//	@Description: 写入 p ，超出 limit 的部分被丢弃，始终返回 len(p)
+//	@receiver b
+//	@param p
+//	@return int
+//	@return error
+func (b *bodyBuffer) Write(p []byte) (int, error) {
+	if b.limit < 0 {
+		return b.buf.Write(p)
+	}
+	room := b.limit - b.buf.Len()
+	if room < len(p) {
+		if room > 0 {
+			b.buf.Write(p[:room])
+		}
+		b.truncated = true
+		return len(p), nil
+	}
+	b.buf.Write(p)
+	return len(p), nil
+}
+
+// Bytes
+//
+//	@Description: 返回已保存的内容
+//	@receiver b
+//	@return []byte
+func (b *bodyBuffer) Bytes() []byte {
+	return b.buf.Bytes()
+}
+
+// Len
+//
+//	@Description: 返回已保存内容的长度
+//	@receiver b
+//	@return int
+func (b *bodyBuffer) Len() int {
+	return b.buf.Len()
+}
+
+// String
+//
+//	@Description: 返回已保存的内容，被截断时追加截断标记
+//	@receiver b
+//	@return string
+func (b *bodyBuffer) String() string {
+	if b.truncated {
+		return b.buf.String() + ginBodyTruncatedMarker
+	}
+	return b.buf.String()
+}
+
+// requestBodyReader 在 handler 读取请求 body 的同时旁路保存读到的内容，避免预先读取整个 body 到内存中
+type requestBodyReader struct {
+	io.ReadCloser
+	body *bodyBuffer
+}
+
+// Read
+//
+//	@Description: 读取请求 body 并将读到的内容写入 body buffer
+//	@receiver r
+//	@param p
+//	@return int
+//	@return error
+func (r *requestBodyReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		_, _ = r.body.Write(p[:n])
+	}
+	return n, err
+}
+
+// drain
+//
+//	@Description: 请求处理完成后补读 handler 未读取的 body ，最多读到超出 limit 一个字节用于判断是否截断
+//	不限制记录长度时最多补读 ginBodyDrainMaxSize 个字节
+//	@receiver r
+func (r *requestBodyReader) drain() {
+	if r.body.truncated {
+		return
+	}
+	if r.body.limit < 0 {
+		if n, _ := io.CopyN(io.Discard, r, ginBodyDrainMaxSize+1); n > ginBodyDrainMaxSize {
+			r.body.truncated = true
+		}
+		return
+	}
+	_, _ = io.CopyN(io.Discard, r, int64(r.body.limit-r.body.Len()+1))
+}
+
+// teeGinRequestBody
+//
+//	@Description: 使用 requestBodyReader 替换请求 body ，handler 读取 body 时会同时保存最多 limit 个字节
+//	@param c
+//	@param limit
+//	@return *requestBodyReader 请求 body 为空时返回 nil
+func teeGinRequestBody(c *gin.Context, limit int) *requestBodyReader {
+	if c.Request == nil || c.Request.Body == nil {
+		return nil
+	}
+	r := &requestBodyReader{ReadCloser: c.Request.Body, body: newBodyBuffer(limit)}
+	c.Request.Body = r
+	return r
+}
+
+// peekedBody 先返回已预读的内容，再继续读取原始 body
+type peekedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// peekGinRequestBody
+//
+//	@Description: 预读请求 body 的前 limit 个字节，预读的内容会在 handler 读取 body 时重新返回
+//	@param c
+//	@param limit
+//	@return []byte
+func peekGinRequestBody(c *gin.Context, limit int) []byte {
+	if c.Request == nil || c.Request.Body == nil || limit <= 0 {
+		return nil
+	}
+	body := c.Request.Body
+	peeked, _ := io.ReadAll(io.LimitReader(body, int64(limit)))
+	c.Request.Body = &peekedBody{Reader: io.MultiReader(bytes.NewReader(peeked), body), Closer: body}
+	return peeked
+}
+
+// matchContentType
+//
+//	@Description: 判断 content type 是否在白名单中，白名单支持 text/* 形式的通配
+//	@param contentType
+//	@param allowlist
+//	@return bool
+func matchContentType(contentType string, allowlist []string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.TrimSpace(strings.Split(contentType, ";")[0])
+	}
+	mediaType = strings.ToLower(mediaType)
+	if mediaType == "" {
+		return false
+	}
+	for _, allowed := range allowlist {
+		allowed = strings.ToLower(allowed)
+		if allowed == mediaType || allowed == "*/*" {
+			return true
+		}
+		if strings.HasSuffix(allowed, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(allowed, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// ginBodyFields
+//
+//	@Description: 根据 content type 白名单生成记录 body 的字段，白名单之外的 body 在开启 base64 时编码后记录，否则不记录
+//	@param key 字段名
+//	@param body
+//	@param contentType
+//	@param allowlist
+//	@param enableBase64
+//	@return []zap.Field
+func ginBodyFields(key string, body *bodyBuffer, contentType string, allowlist []string, enableBase64 bool) []zap.Field {
+	if body == nil {
+		return nil
+	}
+	if matchContentType(contentType, allowlist) {
+		return []zap.Field{zap.String(key, body.String())}
+	}
+	if !enableBase64 || body.Len() == 0 {
+		return nil
+	}
+	encoded := base64.StdEncoding.EncodeToString(body.Bytes())
+	if body.truncated {
+		encoded += ginBodyTruncatedMarker
+	}
+	return []zap.Field{zap.String(key, encoded), zap.String(key+"_encoding", "base64")}
+}
@@ -0,0 +1,239 @@
+package logit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// readTestLogs 读取日志文件中的全部 json 日志
+func readTestLogs(t *testing.T, filename string) []map[string]interface{} {
+	t.Helper()
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var logs []map[string]interface{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	for scanner.Scan() {
+		m := map[string]interface{}{}
+		if err := json.Unmarshal(scanner.Bytes(), &m); err != nil {
+			t.Fatal(err, scanner.Text())
+		}
+		logs = append(logs, m)
+	}
+	return logs
+}
+
+func TestBodyBuffer(t *testing.T) {
+	b := newBodyBuffer(5)
+	n, err := b.Write([]byte("abc"))
+	if n != 3 || err != nil {
+		t.Fatal(n, err)
+	}
+	n, _ = b.Write([]byte("defg"))
+	if n != 4 {
+		t.Error("write should always return len(p)", n)
+	}
+	if b.String() != "abcde"+ginBodyTruncatedMarker {
+		t.Error(b.String())
+	}
+
+	unlimited := newBodyBuffer(-1)
+	unlimited.Write(bytes.Repeat([]byte("a"), 10))
+	if unlimited.String() != strings.Repeat("a", 10) {
+		t.Error(unlimited.String())
+	}
+}
+
+func TestMatchContentType(t *testing.T) {
+	cases := []struct {
+		contentType string
+		want        bool
+	}{
+		{"application/json", true},
+		{"application/json; charset=utf-8", true},
+		{"TEXT/HTML", true},
+		{"text/plain; charset=utf-8", true},
+		{"multipart/form-data; boundary=xx", false},
+		{"application/octet-stream", false},
+		{"image/png", false},
+		{"", false},
+	}
+	for _, c := range cases {
+		if got := matchContentType(c.contentType, defaultGinBodyContentTypes); got != c.want {
+			t.Errorf("matchContentType(%q) = %v, want %v", c.contentType, got, c.want)
+		}
+	}
+}
+
+func TestRequestBodyReaderDrain(t *testing.T) {
+	r := &requestBodyReader{ReadCloser: io.NopCloser(strings.NewReader("0123456789")), body: newBodyBuffer(4)}
+	buf := make([]byte, 2)
+	r.Read(buf)
+	r.drain()
+	if r.body.String() != "0123"+ginBodyTruncatedMarker {
+		t.Error(r.body.String())
+	}
+
+	r = &requestBodyReader{ReadCloser: io.NopCloser(strings.NewReader("0123")), body: newBodyBuffer(4)}
+	r.drain()
+	if r.body.String() != "0123" {
+		t.Error(r.body.String())
+	}
+
+	// 不限制记录长度时最多补读 ginBodyDrainMaxSize 个字节
+	counter := &countingReader{r: bytes.NewReader(make([]byte, 2*ginBodyDrainMaxSize))}
+	r = &requestBodyReader{ReadCloser: io.NopCloser(counter), body: newBodyBuffer(-1)}
+	r.drain()
+	if counter.n > ginBodyDrainMaxSize+1 || !r.body.truncated {
+		t.Error("unlimited drain should be capped", counter.n)
+	}
+}
+
+// countingReader 记录已读取字节数的 reader
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
+
+func TestGinLoggerLargeBody(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	app := gin.New()
+	app.Use(GinLoggerWithConfig(GinLoggerConfig{
+		EnableRequestBody: true,
+		TraceIDFromBody:   true,
+		OutputPaths:       []string{filepath.Join(t.TempDir(), "access.log")},
+	}))
+	counter := &countingReader{r: bytes.NewReader(make([]byte, 10<<20))}
+	readBeforeHandler := -1
+	app.POST("/upload", func(c *gin.Context) {
+		readBeforeHandler = counter.n
+		c.Status(204)
+	})
+	req := httptest.NewRequest(http.MethodPost, "/upload", counter)
+	req.Header.Set("Content-Type", "application/octet-stream")
+	app.ServeHTTP(httptest.NewRecorder(), req)
+	if readBeforeHandler != 0 {
+		t.Error("body should not be read before handler", readBeforeHandler)
+	}
+	if counter.n > defaultGinBodyMaxSize+1 {
+		t.Error("body read should be limited", counter.n)
+	}
+}
+
+func TestGinLoggerTraceIDFromBody(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	logfile := filepath.Join(t.TempDir(), "access.log")
+	app := gin.New()
+	app.Use(GinLoggerWithConfig(GinLoggerConfig{
+		EnableRequestBody:  true,
+		TraceIDFromBody:    true,
+		RequestBodyMaxSize: 64,
+		OutputPaths:        []string{logfile},
+	}))
+	app.POST("/json", func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		c.String(200, string(body))
+	})
+	body := `{"trace_id":"trace-body","key":"value"}`
+	req := httptest.NewRequest(http.MethodPost, "/json", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, req)
+
+	logs := readTestLogs(t, logfile)
+	if w.Body.String() != body || len(logs) != 1 {
+		t.Fatal("handler should read the whole body", w.Body.String(), logs)
+	}
+	if logs[0]["trace_id"] != "trace-body" || logs[0]["request_body"] != body {
+		t.Error("invalid trace id from body", logs[0])
+	}
+}
+
+func TestGinLoggerBodyCapture(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	logfile := filepath.Join(t.TempDir(), "access.log")
+	app := gin.New()
+	app.Use(GinLoggerWithConfig(GinLoggerConfig{
+		EnableRequestBody:   true,
+		EnableResponseBody:  true,
+		RequestBodyMaxSize:  8,
+		ResponseBodyMaxSize: 4,
+		OutputPaths:         []string{logfile},
+	}))
+	app.POST("/json", func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		c.String(200, string(body))
+	})
+	app.POST("/upload", func(c *gin.Context) {
+		c.Data(200, "image/png", []byte("png"))
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/json", strings.NewReader(`{"key":"value"}`))
+	req.Header.Set("Content-Type", "application/json")
+	app.ServeHTTP(httptest.NewRecorder(), req)
+	req = httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("binary"))
+	req.Header.Set("Content-Type", "application/octet-stream")
+	app.ServeHTTP(httptest.NewRecorder(), req)
+
+	logs := readTestLogs(t, logfile)
+	if len(logs) != 2 {
+		t.Fatal("invalid logs count", len(logs))
+	}
+	if logs[0]["request_body"] != `{"key":"`+ginBodyTruncatedMarker {
+		t.Error("request body should be truncated:", logs[0]["request_body"])
+	}
+	if logs[0]["response_body"] != `{"ke`+ginBodyTruncatedMarker {
+		t.Error("response body should be truncated:", logs[0]["response_body"])
+	}
+	if _, exists := logs[1]["request_body"]; exists {
+		t.Error("octet-stream request body should not be logged")
+	}
+	if _, exists := logs[1]["response_body"]; exists {
+		t.Error("image response body should not be logged")
+	}
+}
+
+func TestGinLoggerBodyCaptureBase64(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	logfile := filepath.Join(t.TempDir(), "access.log")
+	app := gin.New()
+	app.Use(GinLoggerWithConfig(GinLoggerConfig{
+		EnableRequestBody:      true,
+		EnableBinaryBodyBase64: true,
+		OutputPaths:            []string{logfile},
+	}))
+	app.POST("/upload", func(c *gin.Context) {
+		c.Status(204)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("binary"))
+	req.Header.Set("Content-Type", "application/octet-stream")
+	app.ServeHTTP(httptest.NewRecorder(), req)
+
+	logs := readTestLogs(t, logfile)
+	if len(logs) != 1 {
+		t.Fatal("invalid logs count", len(logs))
+	}
+	if logs[0]["request_body"] != "YmluYXJ5" || logs[0]["request_body_encoding"] != "base64" {
+		t.Error("binary request body should be logged as base64:", logs[0])
+	}
+}
@@ -0,0 +1,422 @@
+package logit
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oschwald/maxminddb-golang"
+	"go.uber.org/zap"
+)
+
+// GeoIPRecord IP 对应的地理位置和 ASN 信息
+type GeoIPRecord struct {
+	// 国家 ISO 代码，如 CN 、 US
+	Country string
+	// 自治系统编号
+	ASN uint
+	// 自治系统所属组织
+	ASOrg string
+}
+
+// GeoIPReader 根据 IP 查询地理位置和 ASN 信息
+type GeoIPReader interface {
+	Lookup(ip net.IP) (GeoIPRecord, error)
+}
+
+// MaxMindGeoIP 使用本地 MaxMind 格式（ mmdb ）数据库查询 IP 信息
+// 可以同时打开 Country/City 和 ASN 数据库，查询结果会合并
+type MaxMindGeoIP struct {
+	readers []*maxminddb.Reader
+}
+
+// maxMindRecord mmdb 中需要解析的字段
+type maxMindRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	AutonomousSystemNumber       uint   `maxminddb:"autonomous_system_number"`
+	AutonomousSystemOrganization string `maxminddb:"autonomous_system_organization"`
+}
+
+// OpenMaxMindGeoIP
+//
+//	@Description: 打开 MaxMind 格式的数据库文件，如 GeoLite2-Country.mmdb 、 GeoLite2-ASN.mmdb
+//	@param paths
+//	@return *MaxMindGeoIP
+//	@return error
+func OpenMaxMindGeoIP(paths ...string) (*MaxMindGeoIP, error) {
+	if len(paths) == 0 {
+		return nil, errors.New("no maxmind database path")
+	}
+	g := &MaxMindGeoIP{}
+	for _, path := range paths {
+		reader, err := maxminddb.Open(path)
+		if err != nil {
+			_ = g.Close()
+			return nil, err
+		}
+		g.readers = append(g.readers, reader)
+	}
+	return g, nil
+}
+
+// Lookup
+//
+//	@Description: 查询 IP 信息，多个数据库的结果合并
+//	@receiver g
+//	@param ip
+//	@return GeoIPRecord
+//	@return error
+func (g *MaxMindGeoIP) Lookup(ip net.IP) (GeoIPRecord, error) {
+	record := GeoIPRecord{}
+	for _, reader := range g.readers {
+		var r maxMindRecord
+		if err := reader.Lookup(ip, &r); err != nil {
+			return record, err
+		}
+		if r.Country.ISOCode != "" {
+			record.Country = r.Country.ISOCode
+		}
+		if r.AutonomousSystemNumber != 0 {
+			record.ASN = r.AutonomousSystemNumber
+			record.ASOrg = r.AutonomousSystemOrganization
+		}
+	}
+	return record, nil
+}
+
+// Close
+//
+//	@Description: 关闭数据库文件
+//	@receiver g
+//	@return error
+func (g *MaxMindGeoIP) Close() error {
+	var err error
+	for _, reader := range g.readers {
+		if e := reader.Close(); e != nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// UserAgent 从 User-Agent 中解析出的浏览器、系统和设备信息
+type UserAgent struct {
+	Browser        string
+	BrowserVersion string
+	OS             string
+	// 设备类型 desktop 、 mobile 、 tablet 、 bot
+	Device string
+}
+
+// uaBrowserTokens 按优先级排列的浏览器标识， Edge 、 Opera 等基于 Chrome 的浏览器需要排在 Chrome 之前
+var uaBrowserTokens = []struct {
+	token string
+	name  string
+}{
+	{"Edg/", "Edge"},
+	{"EdgA/", "Edge"},
+	{"EdgiOS/", "Edge"},
+	{"OPR/", "Opera"},
+	{"SamsungBrowser/", "Samsung Internet"},
+	{"CriOS/", "Chrome"},
+	{"Chrome/", "Chrome"},
+	{"FxiOS/", "Firefox"},
+	{"Firefox/", "Firefox"},
+	{"MSIE ", "IE"},
+	{"Trident/", "IE"},
+}
+
+// uaBotTokens 爬虫和命令行工具的标识
+var uaBotTokens = []string{"bot", "spider", "crawl", "curl/", "wget/", "python-requests/", "go-http-client/", "okhttp/"}
+
+// ParseUserAgent
+//
+//	@Description: 解析 User-Agent ，只识别常见的浏览器、系统和设备类型，无法识别的字段为空
+//	@param ua
+//	@return UserAgent
+func ParseUserAgent(ua string) UserAgent {
+	result := UserAgent{}
+	if ua == "" {
+		return result
+	}
+	// 爬虫使用包含爬虫标识的 product token 作为名称，如 Googlebot/2.1
+	products := strings.FieldsFunc(ua, func(r rune) bool {
+		return r == ' ' || r == ';' || r == '(' || r == ')' || r == ','
+	})
+	for _, product := range products {
+		lower := strings.ToLower(product + "/")
+		for _, token := range uaBotTokens {
+			if strings.Contains(lower, token) {
+				result.Device = "bot"
+				result.Browser = strings.SplitN(product, "/", 2)[0]
+				result.BrowserVersion = uaVersion(product, result.Browser+"/")
+				return result
+			}
+		}
+	}
+
+	for _, b := range uaBrowserTokens {
+		if strings.Contains(ua, b.token) {
+			result.Browser = b.name
+			result.BrowserVersion = uaVersion(ua, b.token)
+			if b.token == "Trident/" {
+				result.BrowserVersion = uaVersion(ua, "rv:")
+			}
+			break
+		}
+	}
+	if result.Browser == "" && strings.Contains(ua, "Safari/") {
+		result.Browser = "Safari"
+		result.BrowserVersion = uaVersion(ua, "Version/")
+	}
+
+	switch {
+	case strings.Contains(ua, "Windows"):
+		result.OS = "Windows"
+	case strings.Contains(ua, "iPhone") || strings.Contains(ua, "iPad") || strings.Contains(ua, "iPod"):
+		result.OS = "iOS"
+	case strings.Contains(ua, "Android"):
+		result.OS = "Android"
+	case strings.Contains(ua, "CrOS"):
+		result.OS = "Chrome OS"
+	case strings.Contains(ua, "Mac OS X") || strings.Contains(ua, "Macintosh"):
+		result.OS = "macOS"
+	case strings.Contains(ua, "Linux"):
+		result.OS = "Linux"
+	}
+
+	switch {
+	case strings.Contains(ua, "iPad") || strings.Contains(ua, "Tablet") ||
+		(strings.Contains(ua, "Android") && !strings.Contains(ua, "Mobile")):
+		result.Device = "tablet"
+	case strings.Contains(ua, "Mobile") || strings.Contains(ua, "iPhone") || strings.Contains(ua, "iPod"):
+		result.Device = "mobile"
+	default:
+		result.Device = "desktop"
+	}
+	return result
+}
+
+// uaVersion
+//
+//	@Description: 获取 token 之后的版本号
+//	@param ua
+//	@param token
+//	@return string
+func uaVersion(ua, token string) string {
+	i := strings.Index(ua, token)
+	if i < 0 {
+		return ""
+	}
+	version := ua[i+len(token):]
+	end := strings.IndexFunc(version, func(r rune) bool {
+		return !(r >= '0' && r <= '9' || r == '.')
+	})
+	if end >= 0 {
+		version = version[:end]
+	}
+	return version
+}
+
+// ginClientIPResolver 根据可信代理列表从代理请求头中解析真实客户端 IP
+type ginClientIPResolver struct {
+	trusted []*net.IPNet
+}
+
+// newGinClientIPResolver
+//
+//	@Description: 创建客户端 IP 解析器
+//	@param proxies 可信代理的 IP 或 CIDR
+//	@return *ginClientIPResolver
+//	@return error
+func newGinClientIPResolver(proxies []string) (*ginClientIPResolver, error) {
+	r := &ginClientIPResolver{}
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, errors.New("invalid trusted proxy: " + proxy)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 8 * net.IPv4len
+			}
+			r.trusted = append(r.trusted, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, err
+		}
+		r.trusted = append(r.trusted, ipNet)
+	}
+	return r, nil
+}
+
+// isTrusted
+//
+//	@Description: 判断 IP 是否为可信代理
+//	@receiver r
+//	@param ip
+//	@return bool
+func (r *ginClientIPResolver) isTrusted(ip net.IP) bool {
+	for _, ipNet := range r.trusted {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve
+//
+//	@Description: 直连地址为可信代理时，依次使用 Forwarded 、 X-Forwarded-For 、 X-Real-IP 解析客户端 IP
+//	代理链从右往左查找第一个不可信的地址作为客户端 IP ，遇到无法解析的地址时停止查找，使用最后一个可信代理的地址
+//	@receiver r
+//	@param req
+//	@return string
+func (r *ginClientIPResolver) resolve(req *http.Request) string {
+	remote := parseForwardedIP(req.RemoteAddr)
+	if remote == nil {
+		return ""
+	}
+	if !r.isTrusted(remote) {
+		return remote.String()
+	}
+
+	chain := forwardedChain(req.Header.Values("Forwarded"))
+	if len(chain) == 0 {
+		for _, value := range req.Header.Values("X-Forwarded-For") {
+			for _, item := range strings.Split(value, ",") {
+				chain = append(chain, parseForwardedIP(item))
+			}
+		}
+	}
+	// 无法解析的地址之前的部分可能是客户端伪造的，不再继续查找，使用最后一个可信代理的地址
+	peer := remote
+	for i := len(chain) - 1; i >= 0; i-- {
+		if chain[i] == nil {
+			return peer.String()
+		}
+		if !r.isTrusted(chain[i]) {
+			return chain[i].String()
+		}
+		peer = chain[i]
+	}
+	if len(chain) > 0 {
+		return chain[0].String()
+	}
+	if ip := parseForwardedIP(req.Header.Get("X-Real-IP")); ip != nil {
+		return ip.String()
+	}
+	return remote.String()
+}
+
+// forwardedChain
+//
+//	@Description: 解析 RFC 7239 Forwarded 请求头中的 for 地址链
+//	@param values
+//	@return []net.IP 无法解析的地址（如 unknown 、混淆标识）为 nil
+func forwardedChain(values []string) []net.IP {
+	var chain []net.IP
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+				if len(kv) != 2 || !strings.EqualFold(kv[0], "for") {
+					continue
+				}
+				chain = append(chain, parseForwardedIP(kv[1]))
+			}
+		}
+	}
+	return chain
+}
+
+// parseForwardedIP
+//
+//	@Description: 解析代理请求头中的地址，支持带引号、方括号和端口的格式
+//	@param s
+//	@return net.IP 无法解析时返回 nil
+func parseForwardedIP(s string) net.IP {
+	s = strings.Trim(strings.TrimSpace(s), `"`)
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	return net.ParseIP(s)
+}
+
+// ginEnricher 访问日志的客户端 IP 、 User-Agent 和 GeoIP 信息补充
+type ginEnricher struct {
+	resolver  *ginClientIPResolver
+	userAgent bool
+	geoIP     GeoIPReader
+}
+
+// newGinEnricher
+//
+//	@Description: 根据配置创建 ginEnricher ，配置错误时 panic
+//	@param conf
+//	@return *ginEnricher
+func newGinEnricher(conf GinLoggerConfig) *ginEnricher {
+	e := &ginEnricher{userAgent: conf.EnableUserAgentFields, geoIP: conf.GeoIP}
+	if len(conf.TrustedProxies) > 0 {
+		resolver, err := newGinClientIPResolver(conf.TrustedProxies)
+		if err != nil {
+			panic("parse trusted proxies error:" + err.Error())
+		}
+		e.resolver = resolver
+	}
+	return e
+}
+
+// clientIP
+//
+//	@Description: 配置了可信代理时使用解析出的客户端 IP ，否则使用 c.ClientIP()
+//	@receiver e
+//	@param c
+//	@return string
+func (e *ginEnricher) clientIP(c *gin.Context) string {
+	if e.resolver == nil {
+		return c.ClientIP()
+	}
+	return e.resolver.resolve(c.Request)
+}
+
+// fields
+//
+//	@Description: 生成 User-Agent 和 GeoIP 字段
+//	@receiver e
+//	@param c
+//	@param clientIP
+//	@return []zap.Field
+func (e *ginEnricher) fields(c *gin.Context, clientIP string) []zap.Field {
+	var fields []zap.Field
+	if e.userAgent {
+		ua := ParseUserAgent(c.Request.UserAgent())
+		fields = append(fields,
+			zap.String("ua_browser", ua.Browser),
+			zap.String("ua_browser_version", ua.BrowserVersion),
+			zap.String("ua_os", ua.OS),
+			zap.String("ua_device", ua.Device),
+		)
+	}
+	if e.geoIP != nil {
+		if ip := net.ParseIP(clientIP); ip != nil {
+			if record, err := e.geoIP.Lookup(ip); err == nil {
+				fields = append(fields,
+					zap.String("geo_country", record.Country),
+					zap.Uint("geo_asn", record.ASN),
+					zap.String("geo_as_org", record.ASOrg),
+				)
+			}
+		}
+	}
+	return fields
+}
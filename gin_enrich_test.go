@@ -0,0 +1,122 @@
+package logit
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseUserAgent(t *testing.T) {
+	cases := map[string]UserAgent{
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/112.0.0.0 Safari/537.36":                         {"Chrome", "112.0.0.0", "Windows", "desktop"},
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/112.0.0.0 Safari/537.36 Edg/112.0.1722.48":       {"Edge", "112.0.1722.48", "Windows", "desktop"},
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 16_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.4 Mobile/15E148 Safari/604.1": {"Safari", "16.4", "iOS", "mobile"},
+		"Mozilla/5.0 (Linux; Android 13; SM-X700) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/112.0.0.0 Safari/537.36":                          {"Chrome", "112.0.0.0", "Android", "tablet"},
+		"Mozilla/5.0 (Macintosh; Intel Mac OS X 10.15; rv:109.0) Gecko/20100101 Firefox/112.0":                                                    {"Firefox", "112.0", "macOS", "desktop"},
+		"curl/7.88.1": {"curl", "7.88.1", "", "bot"},
+		"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)": {"Googlebot", "2.1", "", "bot"},
+		"": {},
+	}
+	for ua, want := range cases {
+		if got := ParseUserAgent(ua); got != want {
+			t.Errorf("ParseUserAgent(%q) = %+v, want %+v", ua, got, want)
+		}
+	}
+}
+
+func TestGinClientIPResolver(t *testing.T) {
+	r, err := newGinClientIPResolver([]string{"10.0.0.0/8", "192.168.1.1", "2001:db8::/32"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		remote  string
+		headers map[string]string
+		want    string
+	}{
+		// 直连地址不可信，忽略代理请求头
+		{"1.1.1.1:80", map[string]string{"X-Forwarded-For": "2.2.2.2"}, "1.1.1.1"},
+		// 从右往左跳过可信代理
+		{"10.0.0.1:80", map[string]string{"X-Forwarded-For": "3.3.3.3, 2.2.2.2, 10.0.0.2"}, "2.2.2.2"},
+		// 全部可信时使用最左侧的地址
+		{"10.0.0.1:80", map[string]string{"X-Forwarded-For": "10.0.0.3, 10.0.0.2"}, "10.0.0.3"},
+		// Forwarded 优先于 X-Forwarded-For
+		{"192.168.1.1:80", map[string]string{"Forwarded": `for="[2001:db9::1]:4711";proto=https, for=10.0.0.2`, "X-Forwarded-For": "2.2.2.2"}, "2001:db9::1"},
+		{"[2001:db8::1]:80", map[string]string{"X-Real-IP": "4.4.4.4"}, "4.4.4.4"},
+		{"10.0.0.1:80", nil, "10.0.0.1"},
+		// 无法解析的地址之前的部分不可信，使用最后一个可信代理的地址
+		{"10.0.0.1:80", map[string]string{"X-Forwarded-For": "6.6.6.6, garbage, 10.0.0.2"}, "10.0.0.2"},
+		{"10.0.0.1:80", map[string]string{"X-Forwarded-For": "6.6.6.6, garbage"}, "10.0.0.1"},
+		{"10.0.0.1:80", map[string]string{"X-Forwarded-For": "6.6.6.6,, 10.0.0.2"}, "10.0.0.2"},
+		{"192.168.1.1:80", map[string]string{"Forwarded": `for=6.6.6.6, for=unknown, for=10.0.0.2`}, "10.0.0.2"},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = c.remote
+		for k, v := range c.headers {
+			req.Header.Set(k, v)
+		}
+		if got := r.resolve(req); got != c.want {
+			t.Errorf("resolve(%s, %v) = %s, want %s", c.remote, c.headers, got, c.want)
+		}
+	}
+
+	if _, err := newGinClientIPResolver([]string{"not-an-ip"}); err == nil {
+		t.Error("invalid trusted proxy should return error")
+	}
+}
+
+type testGeoIP map[string]GeoIPRecord
+
+func (g testGeoIP) Lookup(ip net.IP) (GeoIPRecord, error) {
+	return g[ip.String()], nil
+}
+
+func TestOpenMaxMindGeoIP(t *testing.T) {
+	if _, err := OpenMaxMindGeoIP(filepath.Join(t.TempDir(), "missing.mmdb")); err == nil {
+		t.Error("open missing database should return error")
+	}
+	if _, err := OpenMaxMindGeoIP(); err == nil {
+		t.Error("open without path should return error")
+	}
+}
+
+func TestGinLoggerEnrichment(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	logfile := filepath.Join(t.TempDir(), "access.log")
+	app := gin.New()
+	app.Use(GinLoggerWithConfig(GinLoggerConfig{
+		TrustedProxies:        []string{"10.0.0.0/8"},
+		EnableUserAgentFields: true,
+		GeoIP:                 testGeoIP{"8.8.8.8": {Country: "US", ASN: 15169, ASOrg: "GOOGLE"}},
+		OutputPaths:           []string{logfile},
+	}))
+	app.GET("/hello", func(c *gin.Context) {
+		c.Status(200)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/hello", nil)
+	req.RemoteAddr = "10.1.1.1:1234"
+	req.Header.Set("X-Forwarded-For", "8.8.8.8, 10.2.2.2")
+	req.Header.Set("User-Agent", "curl/7.88.1")
+	app.ServeHTTP(httptest.NewRecorder(), req)
+
+	logs := readTestLogs(t, logfile)
+	if len(logs) != 1 {
+		t.Fatal("invalid logs count", len(logs))
+	}
+	l := logs[0]
+	if l["client_ip"] != "8.8.8.8" {
+		t.Error("client ip should be resolved through trusted proxies", l["client_ip"])
+	}
+	if l["ua_browser"] != "curl" || l["ua_device"] != "bot" {
+		t.Error("invalid user agent fields", l)
+	}
+	if l["geo_country"] != "US" || l["geo_asn"].(float64) != 15169 || l["geo_as_org"] != "GOOGLE" {
+		t.Error("invalid geoip fields", l)
+	}
+}
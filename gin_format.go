@@ -0,0 +1,341 @@
+package logit
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	// GinLogFormatPipe 默认的 | 分隔格式： client_ip|method|host+uri|status|latency
+	GinLogFormatPipe = "pipe"
+	// GinLogFormatCommon NCSA/Apache common log format
+	GinLogFormatCommon = "common"
+	// GinLogFormatCombined Apache combined log format
+	GinLogFormatCombined = "combined"
+	// GinLogFormatELB 类似 AWS ELB 访问日志的格式
+	GinLogFormatELB = "elb"
+	// GinLogFormatECS msg 为 "method path status" ，并按 Elastic Common Schema 的字段名记录请求信息
+	GinLogFormatECS = "ecs"
+)
+
+// ginLogFormatTemplates 预置格式对应的模板
+var ginLogFormatTemplates = map[string]string{
+	GinLogFormatCommon:   `%h %l %u %t "%r" %>s %b`,
+	GinLogFormatCombined: `%h %l %u %t "%r" %>s %b "%{Referer}i" "%{User-Agent}i"`,
+	GinLogFormatELB:      `%{iso8601}t %v %a - -1 %L -1 %>s - %I %B "%r" "%{User-Agent}i" - -`,
+	GinLogFormatECS:      `%m %U %>s`,
+}
+
+// ginLogFormat 访问日志格式，生成 msg 以及格式附带的字段
+type ginLogFormat struct {
+	formatter func(*gin.Context, GinLogExtends) string
+	fields    func(*gin.Context, GinLogExtends) []zap.Field
+}
+
+// ginLogSegment 模板编译后的片段，将内容追加到 buf 中
+type ginLogSegment func(buf *strings.Builder, c *gin.Context, ext GinLogExtends)
+
+// newGinLogFormat
+//
+//	@Description: 根据配置生成访问日志格式， Formatter 优先于 LogFormat ，都未设置时使用 pipe 格式
+//	@param conf
+//	@return ginLogFormat
+func newGinLogFormat(conf GinLoggerConfig) ginLogFormat {
+	if conf.Formatter != nil {
+		return ginLogFormat{formatter: conf.Formatter}
+	}
+	switch conf.LogFormat {
+	case "", GinLogFormatPipe:
+		return ginLogFormat{formatter: defaultGinLogFormatter}
+	case GinLogFormatECS:
+		return ginLogFormat{formatter: mustCompileGinLogTemplate(ginLogFormatTemplates[GinLogFormatECS]), fields: ginECSFields}
+	}
+	tpl, exists := ginLogFormatTemplates[conf.LogFormat]
+	if !exists {
+		tpl = conf.LogFormat
+	}
+	return ginLogFormat{formatter: mustCompileGinLogTemplate(tpl)}
+}
+
+// mustCompileGinLogTemplate
+//
+//	@Description: 编译访问日志模板，模板错误时 panic
+//	@param tpl
+//	@return func(*gin.Context, GinLogExtends) string
+func mustCompileGinLogTemplate(tpl string) func(*gin.Context, GinLogExtends) string {
+	formatter, err := CompileGinLogTemplate(tpl)
+	if err != nil {
+		panic("compile gin log template " + tpl + " error:" + err.Error())
+	}
+	return formatter
+}
+
+// CompileGinLogTemplate
+//
+//	@Description: 编译 Apache mod_log_config 风格的访问日志模板，返回可以作为 GinLoggerConfig.Formatter 的函数
+//	支持的指令：
+//	%h 客户端 IP ， %a 客户端地址 ip:port ， %l 固定为 - ， %u basic auth 用户名
+//	%t [02/Jan/2006:15:04:05 -0700] 格式的请求时间， %{iso8601}t RFC3339 格式的请求时间
+//	%r 请求行， %m 请求方法， %U 请求路径， %q 查询字符串， %H 协议， %v host
+//	%s %>s 状态码， %b 响应 body 字节数（ 0 时为 - ）， %B 响应 body 字节数， %I 请求 body 字节数
+//	%D 耗时微秒， %T 耗时秒（整数）， %L 耗时秒（小数）
+//	%{Name}i 请求头， %{Name}o 响应头， %% 百分号
+//	{trace_id} trace id ， {key} gin context 中 key 对应的值
+//	@param tpl
+//	@return func(*gin.Context, GinLogExtends) string
+//	@return error
+func CompileGinLogTemplate(tpl string) (func(*gin.Context, GinLogExtends) string, error) {
+	var segments []ginLogSegment
+	literal := strings.Builder{}
+	flush := func() {
+		if literal.Len() > 0 {
+			s := literal.String()
+			segments = append(segments, func(buf *strings.Builder, _ *gin.Context, _ GinLogExtends) {
+				buf.WriteString(s)
+			})
+			literal.Reset()
+		}
+	}
+	for i := 0; i < len(tpl); i++ {
+		switch tpl[i] {
+		case '{':
+			end := strings.IndexByte(tpl[i:], '}')
+			if end < 0 {
+				return nil, errors.New("unclosed { at " + strconv.Itoa(i))
+			}
+			flush()
+			segments = append(segments, ginContextSegment(tpl[i+1:i+end]))
+			i += end
+		case '%':
+			i++
+			if i >= len(tpl) {
+				return nil, errors.New("dangling % at end of template")
+			}
+			if tpl[i] == '%' {
+				literal.WriteByte('%')
+				continue
+			}
+			var param string
+			if tpl[i] == '{' {
+				end := strings.IndexByte(tpl[i:], '}')
+				if end < 0 {
+					return nil, errors.New("unclosed %{ at " + strconv.Itoa(i))
+				}
+				param = tpl[i+1 : i+end]
+				i += end + 1
+			} else if tpl[i] == '>' {
+				i++
+			}
+			if i >= len(tpl) {
+				return nil, errors.New("missing directive at end of template")
+			}
+			segment, err := ginDirectiveSegment(tpl[i], param)
+			if err != nil {
+				return nil, err
+			}
+			flush()
+			segments = append(segments, segment)
+		default:
+			literal.WriteByte(tpl[i])
+		}
+	}
+	flush()
+
+	return func(c *gin.Context, ext GinLogExtends) string {
+		buf := strings.Builder{}
+		for _, segment := range segments {
+			segment(&buf, c, ext)
+		}
+		return buf.String()
+	}, nil
+}
+
+// ginContextSegment
+//
+//	@Description: {key} 片段， trace_id 使用请求的 trace id ，其他 key 从 gin context 中获取
+//	@param key
+//	@return ginLogSegment
+func ginContextSegment(key string) ginLogSegment {
+	if key == string(TraceIDKeyName) {
+		return func(buf *strings.Builder, _ *gin.Context, ext GinLogExtends) {
+			buf.WriteString(dashIfEmpty(ext.TraceID))
+		}
+	}
+	return func(buf *strings.Builder, c *gin.Context, _ GinLogExtends) {
+		v, exists := c.Get(key)
+		if !exists || v == nil {
+			buf.WriteString("-")
+			return
+		}
+		buf.WriteString(dashIfEmpty(fmt.Sprint(v)))
+	}
+}
+
+// ginDirectiveSegment
+//
+//	@Description: % 指令片段
+//	@param directive
+//	@param param %{param}x 中的参数
+//	@return ginLogSegment
+//	@return error
+func ginDirectiveSegment(directive byte, param string) (ginLogSegment, error) {
+	var segment ginLogSegment
+	switch directive {
+	case 'h':
+		segment = func(buf *strings.Builder, c *gin.Context, ext GinLogExtends) {
+			buf.WriteString(dashIfEmpty(ext.ClientIP))
+		}
+	case 'a':
+		segment = func(buf *strings.Builder, c *gin.Context, _ GinLogExtends) {
+			buf.WriteString(dashIfEmpty(c.Request.RemoteAddr))
+		}
+	case 'l':
+		segment = func(buf *strings.Builder, _ *gin.Context, _ GinLogExtends) {
+			buf.WriteString("-")
+		}
+	case 'u':
+		segment = func(buf *strings.Builder, c *gin.Context, _ GinLogExtends) {
+			user, _, _ := c.Request.BasicAuth()
+			buf.WriteString(dashIfEmpty(user))
+		}
+	case 't':
+		layout := "[02/Jan/2006:15:04:05 -0700]"
+		switch param {
+		case "":
+		case "iso8601":
+			layout = time.RFC3339Nano
+		default:
+			return nil, errors.New("unsupported time format %{" + param + "}t")
+		}
+		segment = func(buf *strings.Builder, _ *gin.Context, ext GinLogExtends) {
+			buf.WriteString(ext.ReqTime.Format(layout))
+		}
+	case 'r':
+		segment = func(buf *strings.Builder, _ *gin.Context, ext GinLogExtends) {
+			buf.WriteString(ext.Method + " " + ext.RequestURI + " " + ext.Proto)
+		}
+	case 'm':
+		segment = func(buf *strings.Builder, _ *gin.Context, ext GinLogExtends) {
+			buf.WriteString(ext.Method)
+		}
+	case 'U':
+		segment = func(buf *strings.Builder, _ *gin.Context, ext GinLogExtends) {
+			buf.WriteString(ext.Path)
+		}
+	case 'q':
+		segment = func(buf *strings.Builder, c *gin.Context, _ GinLogExtends) {
+			if c.Request.URL.RawQuery != "" {
+				buf.WriteString("?" + c.Request.URL.RawQuery)
+			}
+		}
+	case 'H':
+		segment = func(buf *strings.Builder, _ *gin.Context, ext GinLogExtends) {
+			buf.WriteString(ext.Proto)
+		}
+	case 'v':
+		segment = func(buf *strings.Builder, _ *gin.Context, ext GinLogExtends) {
+			buf.WriteString(dashIfEmpty(ext.Host))
+		}
+	case 's':
+		segment = func(buf *strings.Builder, _ *gin.Context, ext GinLogExtends) {
+			buf.WriteString(strconv.Itoa(ext.StatusCode))
+		}
+	case 'b':
+		segment = func(buf *strings.Builder, _ *gin.Context, ext GinLogExtends) {
+			if size := ext.BodySize; size > 0 {
+				buf.WriteString(strconv.Itoa(size))
+			} else {
+				buf.WriteString("-")
+			}
+		}
+	case 'B':
+		segment = func(buf *strings.Builder, _ *gin.Context, ext GinLogExtends) {
+			size := ext.BodySize
+			if size < 0 {
+				size = 0
+			}
+			buf.WriteString(strconv.Itoa(size))
+		}
+	case 'I':
+		segment = func(buf *strings.Builder, c *gin.Context, _ GinLogExtends) {
+			size := c.Request.ContentLength
+			if size < 0 {
+				size = 0
+			}
+			buf.WriteString(strconv.FormatInt(size, 10))
+		}
+	case 'D':
+		segment = func(buf *strings.Builder, _ *gin.Context, ext GinLogExtends) {
+			buf.WriteString(strconv.FormatInt(int64(ext.Latency*1e6), 10))
+		}
+	case 'T':
+		segment = func(buf *strings.Builder, _ *gin.Context, ext GinLogExtends) {
+			buf.WriteString(strconv.FormatInt(int64(ext.Latency), 10))
+		}
+	case 'L':
+		segment = func(buf *strings.Builder, _ *gin.Context, ext GinLogExtends) {
+			buf.WriteString(strconv.FormatFloat(ext.Latency, 'f', 6, 64))
+		}
+	case 'i':
+		if param == "" {
+			return nil, errors.New("%i requires a header name like %{User-Agent}i")
+		}
+		segment = func(buf *strings.Builder, c *gin.Context, _ GinLogExtends) {
+			buf.WriteString(dashIfEmpty(c.Request.Header.Get(param)))
+		}
+	case 'o':
+		if param == "" {
+			return nil, errors.New("%o requires a header name like %{Content-Type}o")
+		}
+		segment = func(buf *strings.Builder, c *gin.Context, _ GinLogExtends) {
+			buf.WriteString(dashIfEmpty(c.Writer.Header().Get(param)))
+		}
+	default:
+		return nil, errors.New("unsupported directive %" + string(directive))
+	}
+	if param != "" && directive != 't' && directive != 'i' && directive != 'o' {
+		return nil, errors.New("directive %" + string(directive) + " does not accept parameter")
+	}
+	return segment, nil
+}
+
+// ginECSFields
+//
+//	@Description: 按 Elastic Common Schema 字段名记录的请求信息
+//	@param c
+//	@param ext
+//	@return []zap.Field
+func ginECSFields(c *gin.Context, ext GinLogExtends) []zap.Field {
+	return []zap.Field{
+		zap.String("client.ip", ext.ClientIP),
+		zap.String("http.request.method", ext.Method),
+		zap.String("http.request.referrer", c.Request.Referer()),
+		zap.String("http.version", strings.TrimPrefix(ext.Proto, "HTTP/")),
+		zap.Int("http.response.status_code", ext.StatusCode),
+		zap.Int("http.response.body.bytes", ext.BodySize),
+		zap.String("url.domain", ext.Host),
+		zap.String("url.path", ext.Path),
+		zap.String("url.query", c.Request.URL.RawQuery),
+		zap.String("user_agent.original", c.Request.UserAgent()),
+		zap.Int64("event.duration", int64(ext.Latency*1e9)),
+		zap.String("trace.id", ext.TraceID),
+	}
+}
+
+// dashIfEmpty
+//
+//	@Description: 空字符串返回 - ，与 Apache 日志格式保持一致
+//	@param s
+//	@return string
+func dashIfEmpty(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
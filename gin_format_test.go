@@ -0,0 +1,98 @@
+package logit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCompileGinLogTemplate(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/hello?k=v", nil)
+	c.Request.RemoteAddr = "10.0.0.1:1234"
+	c.Request.Header.Set("Referer", "http://example.com")
+	c.Request.SetBasicAuth("frank", "secret")
+	c.Set("user_id", 42)
+	c.String(200, "world")
+	ext := GinLogExtends{
+		Latency:    0.0123,
+		ReqTime:    time.Date(2000, 10, 10, 13, 55, 36, 0, time.FixedZone("", -7*3600)),
+		TraceID:    "trace-1",
+		ClientIP:   "10.0.0.1",
+		Method:     http.MethodGet,
+		Path:       "/hello",
+		Host:       c.Request.Host,
+		RequestURI: "/hello?k=v",
+		Proto:      "HTTP/1.1",
+		StatusCode: 200,
+		BodySize:   5,
+	}
+
+	cases := map[string]string{
+		ginLogFormatTemplates[GinLogFormatCommon]:             `10.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /hello?k=v HTTP/1.1" 200 5`,
+		ginLogFormatTemplates[GinLogFormatCombined]:           `10.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /hello?k=v HTTP/1.1" 200 5 "http://example.com" "-"`,
+		`%m %U%q %>s %D {trace_id} {user_id} {missing} 100%%`: `GET /hello?k=v 200 12300 trace-1 42 - 100%`,
+		`%{iso8601}t %a %L %{Content-Type}o`:                  `2000-10-10T13:55:36-07:00 10.0.0.1:1234 0.012300 text/plain; charset=utf-8`,
+	}
+	for tpl, want := range cases {
+		formatter, err := CompileGinLogTemplate(tpl)
+		if err != nil {
+			t.Fatal(tpl, err)
+		}
+		if got := formatter(c, ext); got != want {
+			t.Errorf("template %q\n got: %s\nwant: %s", tpl, got, want)
+		}
+	}
+
+	for _, tpl := range []string{"%x", "%", "{trace_id", "%{User-Agent", "%i", "%{x}h", "%{unix}t"} {
+		if _, err := CompileGinLogTemplate(tpl); err == nil {
+			t.Errorf("template %q should be invalid", tpl)
+		}
+	}
+}
+
+func TestGinLoggerLogFormat(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	logfile := filepath.Join(t.TempDir(), "access.log")
+	for _, format := range []string{GinLogFormatCombined, GinLogFormatECS, `%>s {trace_id}`} {
+		app := gin.New()
+		app.Use(GinLoggerWithConfig(GinLoggerConfig{
+			LogFormat:   format,
+			TraceIDFunc: func(*gin.Context) string { return "trace-format" },
+			OutputPaths: []string{logfile},
+		}))
+		app.GET("/hello", func(c *gin.Context) {
+			c.String(200, "world")
+		})
+		app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/hello", nil))
+	}
+
+	logs := readTestLogs(t, logfile)
+	if len(logs) != 3 {
+		t.Fatal("invalid logs count", len(logs))
+	}
+	if msg := logs[0]["msg"].(string); msg[len(msg)-len(`200 5 "-" "-"`):] != `200 5 "-" "-"` {
+		t.Error("invalid combined msg", msg)
+	}
+	if logs[1]["msg"] != "GET /hello 200" || logs[1]["trace.id"] != "trace-format" || logs[1]["http.response.status_code"].(float64) != 200 {
+		t.Error("invalid ecs log", logs[1])
+	}
+	if logs[2]["msg"] != "200 trace-format" {
+		t.Error("invalid template msg", logs[2]["msg"])
+	}
+}
+
+func TestGinLoggerInvalidLogFormat(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("invalid log format should panic")
+		}
+	}()
+	GinLoggerWithConfig(GinLoggerConfig{LogFormat: "%z"})
+}
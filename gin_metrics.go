@@ -0,0 +1,261 @@
+package logit
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// 默认指标名前缀
+	defaultGinMetricsNamespace = "http"
+	// 未匹配到路由（如 404 ）的请求使用的路由标签
+	ginMetricsUnmatchedRoute = "unmatched"
+	// 非标准请求方法使用的方法标签
+	ginMetricsOtherMethod = "other"
+	// Prometheus 文本格式的 content type
+	metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+// 默认的耗时直方图分桶 (秒)，与 Prometheus client 的默认分桶一致
+var defaultGinMetricsBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// ginMetricsMethods 使用原始值作为标签的标准请求方法，其他方法由客户端任意指定，统一使用 other 避免指标数量无限增长
+var ginMetricsMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodConnect: true,
+	http.MethodOptions: true,
+	http.MethodTrace:   true,
+}
+
+// GinMetricsConfig GinMetrics 支持的配置项字段定义
+type GinMetricsConfig struct {
+	// 指标名前缀，生成 <Namespace>_requests_total 和 <Namespace>_request_duration_seconds 指标
+	// Optional. Default value is http
+	Namespace string
+	// 耗时直方图分桶上限 (秒)，需要升序排列
+	// Optional. 默认 .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10
+	Buckets []float64
+}
+
+// ginMetricsLabels 指标标签
+type ginMetricsLabels struct {
+	route  string
+	method string
+	status string
+}
+
+// ginMetricsSeries 单组标签的请求数和耗时直方图
+type ginMetricsSeries struct {
+	count   uint64
+	sum     float64
+	buckets []uint64
+}
+
+// GinMetrics 访问日志指标，按路由模板、请求方法和状态码分类统计请求数和耗时直方图
+// 设置到 GinLoggerConfig.Metrics 后由 GinLogger 中间件记录，通过 Handler 以 Prometheus 文本格式输出
+type GinMetrics struct {
+	namespace string
+	buckets   []float64
+
+	mu     sync.Mutex
+	series map[ginMetricsLabels]*ginMetricsSeries
+}
+
+// NewGinMetrics
+//
+//	@Description: 创建访问日志指标，分桶配置错误时 panic
+//	@param conf
+//	@return *GinMetrics
+func NewGinMetrics(conf GinMetricsConfig) *GinMetrics {
+	if conf.Namespace == "" {
+		conf.Namespace = defaultGinMetricsNamespace
+	}
+	if len(conf.Buckets) == 0 {
+		conf.Buckets = defaultGinMetricsBuckets
+	}
+	for i := 1; i < len(conf.Buckets); i++ {
+		if conf.Buckets[i] <= conf.Buckets[i-1] {
+			panic("gin metrics buckets must be in increasing order")
+		}
+	}
+	return &GinMetrics{
+		namespace: conf.Namespace,
+		buckets:   append([]float64(nil), conf.Buckets...),
+		series:    map[ginMetricsLabels]*ginMetricsSeries{},
+	}
+}
+
+// ginMetricsStatusClass
+//
+//	@Description: 状态码分类，如 2xx 、 5xx
+//	@param status
+//	@return string
+func ginMetricsStatusClass(status int) string {
+	if status < 100 || status > 599 {
+		return "unknown"
+	}
+	return strconv.Itoa(status/100) + "xx"
+}
+
+// observe
+//
+//	@Description: 记录一次请求
+//	@receiver m
+//	@param route 路由模板，即 c.FullPath()
+//	@param method
+//	@param status
+//	@param latency 请求耗时 (秒)
+func (m *GinMetrics) observe(route, method string, status int, latency float64) {
+	// 未匹配路由的 path 和非标准方法由客户端任意指定，不能直接作为标签
+	if route == "" {
+		route = ginMetricsUnmatchedRoute
+	}
+	if !ginMetricsMethods[method] {
+		method = ginMetricsOtherMethod
+	}
+	labels := ginMetricsLabels{route: route, method: method, status: ginMetricsStatusClass(status)}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, exists := m.series[labels]
+	if !exists {
+		s = &ginMetricsSeries{buckets: make([]uint64, len(m.buckets))}
+		m.series[labels] = s
+	}
+	s.count++
+	s.sum += latency
+	for i, upper := range m.buckets {
+		if latency <= upper {
+			s.buckets[i]++
+		}
+	}
+}
+
+// metricsLabelValue
+//
+//	@Description: 转义 Prometheus 文本格式标签值中的 \ 、 " 和换行
+//	@param v
+//	@return string
+func metricsLabelValue(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
+
+// ginMetricsFloat
+//
+//	@Description: 格式化指标值
+//	@param v
+//	@return string
+func ginMetricsFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// WriteTo
+//
+//	@Description: 以 Prometheus 文本格式输出全部指标
+//	@receiver m
+//	@param w
+//	@return int64
+//	@return error
+func (m *GinMetrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	labels := make([]ginMetricsLabels, 0, len(m.series))
+	series := make(map[ginMetricsLabels]ginMetricsSeries, len(m.series))
+	for l, s := range m.series {
+		labels = append(labels, l)
+		series[l] = ginMetricsSeries{count: s.count, sum: s.sum, buckets: append([]uint64(nil), s.buckets...)}
+	}
+	m.mu.Unlock()
+	sort.Slice(labels, func(i, j int) bool {
+		if labels[i].route != labels[j].route {
+			return labels[i].route < labels[j].route
+		}
+		if labels[i].method != labels[j].method {
+			return labels[i].method < labels[j].method
+		}
+		return labels[i].status < labels[j].status
+	})
+
+	cw := &countWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	total := m.namespace + "_requests_total"
+	bw.WriteString("# HELP " + total + " Total number of HTTP requests.\n")
+	bw.WriteString("# TYPE " + total + " counter\n")
+	for _, l := range labels {
+		bw.WriteString(total + "{" + l.String() + "} " + strconv.FormatUint(series[l].count, 10) + "\n")
+	}
+	duration := m.namespace + "_request_duration_seconds"
+	bw.WriteString("# HELP " + duration + " HTTP request latency in seconds.\n")
+	bw.WriteString("# TYPE " + duration + " histogram\n")
+	for _, l := range labels {
+		s := series[l]
+		for i, upper := range m.buckets {
+			bw.WriteString(duration + "_bucket{" + l.String() + `,le="` + ginMetricsFloat(upper) + `"} ` + strconv.FormatUint(s.buckets[i], 10) + "\n")
+		}
+		bw.WriteString(duration + "_bucket{" + l.String() + `,le="+Inf"} ` + strconv.FormatUint(s.count, 10) + "\n")
+		bw.WriteString(duration + "_sum{" + l.String() + "} " + ginMetricsFloat(s.sum) + "\n")
+		bw.WriteString(duration + "_count{" + l.String() + "} " + strconv.FormatUint(s.count, 10) + "\n")
+	}
+	err := bw.Flush()
+	return cw.n, err
+}
+
+// String
+//
+//	@Description: 标签的文本格式
+//	@receiver l
+//	@return string
+func (l ginMetricsLabels) String() string {
+	return `route="` + metricsLabelValue(l.route) + `",method="` + metricsLabelValue(l.method) + `",status="` + l.status + `"`
+}
+
+// ServeHTTP
+//
+//	@Description: 实现 http.Handler ，以 Prometheus 文本格式输出指标
+//	@receiver m
+//	@param w
+//	@param r
+func (m *GinMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", metricsContentType)
+	_, _ = m.WriteTo(w)
+}
+
+// Handler
+//
+//	@Description: 生成输出指标的 gin handler ，如 app.GET("/metrics", metrics.Handler())
+//	@receiver m
+//	@return gin.HandlerFunc
+func (m *GinMetrics) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		m.ServeHTTP(c.Writer, c.Request)
+	}
+}
+
+// countWriter 记录写入字节数的 io.Writer
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+// Write
+//
+//	@Description: 写入并累加字节数
+//	@receiver w
+//	@param p
+//	@return int
+//	@return error
+func (w *countWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
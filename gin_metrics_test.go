@@ -0,0 +1,96 @@
+package logit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGinMetrics(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	metrics := NewGinMetrics(GinMetricsConfig{Namespace: "api", Buckets: []float64{0.5, 1}})
+	app := gin.New()
+	app.Use(GinLoggerWithConfig(GinLoggerConfig{
+		Metrics:     metrics,
+		SkipPaths:   []string{"/metrics"},
+		OutputPaths: []string{filepath.Join(t.TempDir(), "access.log")},
+	}))
+	app.GET("/orders/:id", func(c *gin.Context) {
+		c.String(200, "ok")
+	})
+	app.GET("/metrics", metrics.Handler())
+	app.Handle("PURGE", "/orders/:id", func(c *gin.Context) {
+		c.String(200, "ok")
+	})
+	for _, target := range []string{"/orders/1", "/orders/2", "/missing"} {
+		app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+	// 客户端指定的非标准方法和未匹配路由不会产生新的指标
+	for _, method := range []string{"FOO1", "FOO2", "PURGE"} {
+		app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/orders/"+method, nil))
+		app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/random/"+method, nil))
+	}
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Error("invalid content type", ct)
+	}
+	body := w.Body.String()
+	for _, line := range []string{
+		"# TYPE api_requests_total counter",
+		`api_requests_total{route="/orders/:id",method="GET",status="2xx"} 2`,
+		`api_requests_total{route="unmatched",method="GET",status="4xx"} 1`,
+		"# TYPE api_request_duration_seconds histogram",
+		`api_request_duration_seconds_bucket{route="/orders/:id",method="GET",status="2xx",le="0.5"} 2`,
+		`api_request_duration_seconds_bucket{route="/orders/:id",method="GET",status="2xx",le="+Inf"} 2`,
+		`api_request_duration_seconds_count{route="unmatched",method="GET",status="4xx"} 1`,
+		`api_requests_total{route="/orders/:id",method="other",status="2xx"} 1`,
+		`api_requests_total{route="unmatched",method="other",status="4xx"} 5`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("metrics missing %q\n%s", line, body)
+		}
+	}
+	if strings.Contains(body, "FOO") || strings.Contains(body, "PURGE") || strings.Contains(body, "/random") {
+		t.Error("client supplied methods and paths should not be labels", body)
+	}
+	if strings.Contains(body, `route="/metrics"`) {
+		t.Error("skipped path should not be observed")
+	}
+}
+
+func TestGinMetricsObserve(t *testing.T) {
+	metrics := NewGinMetrics(GinMetricsConfig{Buckets: []float64{0.1, 1}})
+	metrics.observe("/a", "POST", 503, 0.5)
+	metrics.observe("/a", "POST", 500, 2)
+	metrics.observe("/a\"b", "GET", 0, 0.01)
+
+	var buf strings.Builder
+	n, err := metrics.WriteTo(&buf)
+	if err != nil || n != int64(buf.Len()) {
+		t.Fatal(n, err)
+	}
+	for _, line := range []string{
+		`http_request_duration_seconds_bucket{route="/a",method="POST",status="5xx",le="0.1"} 0`,
+		`http_request_duration_seconds_bucket{route="/a",method="POST",status="5xx",le="1"} 1`,
+		`http_request_duration_seconds_bucket{route="/a",method="POST",status="5xx",le="+Inf"} 2`,
+		`http_request_duration_seconds_sum{route="/a",method="POST",status="5xx"} 2.5`,
+		`http_requests_total{route="/a\"b",method="GET",status="unknown"} 1`,
+	} {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Errorf("metrics missing %q\n%s", line, buf.String())
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("unsorted buckets should panic")
+		}
+	}()
+	NewGinMetrics(GinMetricsConfig{Buckets: []float64{1, 0.5}})
+}
@@ -0,0 +1,154 @@
+package logit
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ginResponseRecorder 包装 gin.ResponseWriter ，记录响应 body 、首字节耗时和写入次数
+// 所有写入路径（Write 、 WriteString ）都会被记录， Flush 、 Hijack 、 CloseNotify 直接透传给原始 writer
+// 调用过 Flush 或 Content-Type 为 text/event-stream 的响应视为流式响应，只记录写入次数不记录 body
+type ginResponseRecorder struct {
+	gin.ResponseWriter
+	// 响应 body ， nil 表示不记录
+	body *bodyBuffer
+	// 请求开始时间
+	start time.Time
+	// 第一次写入 body 的时间
+	firstByteAt time.Time
+	// body 写入次数
+	chunks int
+	// 是否调用过 Flush
+	flushed bool
+}
+
+// newGinResponseRecorder
+//
+//	@Description: 创建 ginResponseRecorder
+//	@param w 原始 writer
+//	@param start 请求开始时间，用于计算首字节耗时
+//	@param body 保存响应 body 的 buffer ，传 nil 不记录 body
+//	@return *ginResponseRecorder
+func newGinResponseRecorder(w gin.ResponseWriter, start time.Time, body *bodyBuffer) *ginResponseRecorder {
+	return &ginResponseRecorder{ResponseWriter: w, start: start, body: body}
+}
+
+// observe
+//
+//	@Description: 记录一次 body 写入
+//	@receiver w
+func (w *ginResponseRecorder) observe() {
+	if w.firstByteAt.IsZero() {
+		w.firstByteAt = time.Now()
+	}
+	w.chunks++
+}
+
+// Write
+//
+//	@Description: 覆盖 ResponseWriter 接口的 Write 方法，记录写入的 body
+//	@receiver w
+//	@param b
+//	@return int
+//	@return error
+func (w *ginResponseRecorder) Write(b []byte) (int, error) {
+	w.observe()
+	if w.body != nil && !w.Streaming() {
+		_, _ = w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// WriteString
+//
+//	@Description: 覆盖 ResponseWriter 接口的 WriteString 方法， c.String 等使用该方法写入 body
+//	@receiver w
+//	@param s
+//	@return int
+//	@return error
+func (w *ginResponseRecorder) WriteString(s string) (int, error) {
+	w.observe()
+	if w.body != nil && !w.Streaming() {
+		_, _ = w.body.Write([]byte(s))
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Flush
+//
+//	@Description: 透传 Flush ，调用后响应被视为流式响应
+//	@receiver w
+func (w *ginResponseRecorder) Flush() {
+	w.flushed = true
+	w.ResponseWriter.Flush()
+}
+
+// Hijack
+//
+//	@Description: 透传 Hijack
+//	@receiver w
+//	@return net.Conn
+//	@return *bufio.ReadWriter
+//	@return error
+func (w *ginResponseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.ResponseWriter.Hijack()
+}
+
+// CloseNotify
+//
+//	@Description: 透传 CloseNotify
+//	@receiver w
+//	@return <-chan bool
+func (w *ginResponseRecorder) CloseNotify() <-chan bool {
+	return w.ResponseWriter.CloseNotify()
+}
+
+// Unwrap
+//
+//	@Description: 返回原始 writer ，供 http.ResponseController 使用
+//	@receiver w
+//	@return http.ResponseWriter
+func (w *ginResponseRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Streaming
+//
+//	@Description: 是否为流式响应
+//	@receiver w
+//	@return bool
+func (w *ginResponseRecorder) Streaming() bool {
+	if w.flushed {
+		return true
+	}
+	return matchContentType(w.Header().Get("Content-Type"), []string{"text/event-stream"})
+}
+
+// FirstByteLatency
+//
+//	@Description: 返回从请求开始到第一次写入 body 的耗时，未写入 body 时返回 0
+//	@receiver w
+//	@return time.Duration
+func (w *ginResponseRecorder) FirstByteLatency() time.Duration {
+	if w.firstByteAt.IsZero() {
+		return 0
+	}
+	return w.firstByteAt.Sub(w.start)
+}
+
+// streamingFields
+//
+//	@Description: 流式响应记录写入次数代替 body
+//	@receiver w
+//	@return []zap.Field
+func (w *ginResponseRecorder) streamingFields() []zap.Field {
+	if !w.Streaming() {
+		return nil
+	}
+	return []zap.Field{zap.Bool("response_streaming", true), zap.Int("response_chunks", w.chunks)}
+}
@@ -0,0 +1,83 @@
+package logit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGinResponseRecorder(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	start := time.Now()
+	r := newGinResponseRecorder(c.Writer, start, newBodyBuffer(-1))
+	if r.FirstByteLatency() != 0 {
+		t.Error("first byte latency should be 0 before writing")
+	}
+	r.WriteString("hello ")
+	r.Write([]byte("world"))
+	if r.body.String() != "hello world" {
+		t.Error("all write paths should be recorded:", r.body.String())
+	}
+	if r.chunks != 2 {
+		t.Error("invalid chunks", r.chunks)
+	}
+	if r.FirstByteLatency() <= 0 {
+		t.Error("first byte latency should be recorded")
+	}
+	if r.Streaming() {
+		t.Error("should not be streaming before flush")
+	}
+	r.Flush()
+	r.Write([]byte("!"))
+	if !r.Streaming() {
+		t.Error("should be streaming after flush")
+	}
+	if r.body.String() != "hello world" {
+		t.Error("streaming writes should not be recorded:", r.body.String())
+	}
+}
+
+func TestGinLoggerResponseRecorder(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	logfile := filepath.Join(t.TempDir(), "access.log")
+	app := gin.New()
+	app.Use(GinLoggerWithConfig(GinLoggerConfig{
+		EnableResponseBody: true,
+		OutputPaths:        []string{logfile},
+	}))
+	app.GET("/string", func(c *gin.Context) {
+		c.String(200, "pong")
+	})
+	app.GET("/stream", func(c *gin.Context) {
+		c.Header("Content-Type", "text/event-stream")
+		for i := 0; i < 3; i++ {
+			c.SSEvent("message", i)
+			c.Writer.Flush()
+		}
+	})
+
+	app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/string", nil))
+	app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/stream", nil))
+
+	logs := readTestLogs(t, logfile)
+	if len(logs) != 2 {
+		t.Fatal("invalid logs count", len(logs))
+	}
+	if logs[0]["response_body"] != "pong" {
+		t.Error("c.String response body should be recorded:", logs[0]["response_body"])
+	}
+	if _, exists := logs[0]["first_byte_latency_seconds"]; !exists {
+		t.Error("first byte latency should be logged")
+	}
+	if _, exists := logs[1]["response_body"]; exists {
+		t.Error("streaming response body should not be logged")
+	}
+	if logs[1]["response_streaming"] != true || logs[1]["response_chunks"].(float64) < 3 {
+		t.Error("streaming response should log chunks:", logs[1])
+	}
+}
@@ -0,0 +1,125 @@
+package logit
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// 默认 recovery logger name
+	defaultGinRecoveryLoggerName = "recovery"
+)
+
+// GinRecoveryConfig GinRecovery 支持的配置项字段定义
+type GinRecoveryConfig struct {
+	// logger name ，默认为 recovery
+	// Optional.
+	Name string
+	// 捕获 panic 并打印日志后调用，用于自定义响应，默认返回 500 状态码
+	// 客户端断开连接（ broken pipe ）时不会调用
+	// Optional.
+	Handler gin.RecoveryFunc
+}
+
+// GinRecovery
+//
+//	@Description: 以默认配置生成 gin 的 Recovery 中间件
+//	@return gin.HandlerFunc
+func GinRecovery() gin.HandlerFunc {
+	return GinRecoveryWithConfig(GinRecoveryConfig{})
+}
+
+// defaultGinRecoveryHandler
+//
+//	@Description: 默认的 panic 响应，返回 500 状态码
+//	@param c
+//	@param err
+func defaultGinRecoveryHandler(c *gin.Context, err interface{}) {
+	c.AbortWithStatus(http.StatusInternalServerError)
+}
+
+// GinRecoveryWithConfig
+//
+//	@Description: 根据配置信息生成 gin 的 Recovery 中间件
+//	捕获 panic 后使用 ctx logger 打印带 trace id 、 stacktrace 和请求详细信息的 error 日志
+//	需要在 GinLogger 之后 Use ，访问日志中会记录 500 状态码和 panic 信息
+//	客户端断开连接导致的 panic 不打印 stacktrace ，也不再写入响应
+//	@param conf
+//	@return gin.HandlerFunc
+func GinRecoveryWithConfig(conf GinRecoveryConfig) gin.HandlerFunc {
+	handler := conf.Handler
+	if handler == nil {
+		handler = defaultGinRecoveryHandler
+	}
+	if conf.Name == "" {
+		conf.Name = defaultGinRecoveryLoggerName
+	}
+
+	return func(c *gin.Context) {
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+			// 关闭 logger 自动添加的 stacktrace ，只在 panic 日志中手动记录
+			logger := CtxLogger(c).Named(conf.Name).WithOptions(zap.AddStacktrace(zapcore.FatalLevel))
+			fields := []zap.Field{
+				zap.Any("panic", err),
+				zap.String("client_ip", ginClientIP(c)),
+				zap.String("method", c.Request.Method),
+				zap.String("path", c.Request.URL.Path),
+				zap.String("host", c.Request.Host),
+			}
+			fields = append(fields, ginDetailFields(c)...)
+			fields = append(fields,
+				zap.Any("context_keys", c.Keys),
+				zap.Any("request_header", c.Request.Header),
+				zap.Any("request_form", c.Request.Form),
+			)
+			// 只记录 handler 已读取的 body ，不再读取连接
+			fields = append(fields, ginRequestBodyFields(c)...)
+
+			if isBrokenPipeError(err) {
+				// 连接已断开，无法再写入响应
+				logger.Error("recovery from broken pipe", fields...)
+				_ = c.Error(fmt.Errorf("broken pipe: %v", err))
+				c.Abort()
+				return
+			}
+			logger.Error("recovery from panic", append(fields, zap.Stack("stacktrace"))...)
+			_ = c.Error(fmt.Errorf("panic: %v", err))
+			handler(c, err)
+		}()
+		c.Next()
+	}
+}
+
+// isBrokenPipeError
+//
+//	@Description: 判断 panic 是否由客户端断开连接引起
+//	@param err
+//	@return bool
+func isBrokenPipeError(err interface{}) bool {
+	e, ok := err.(error)
+	if !ok {
+		return false
+	}
+	var opErr *net.OpError
+	if !errors.As(e, &opErr) {
+		return false
+	}
+	var syscallErr *os.SyscallError
+	if !errors.As(opErr, &syscallErr) {
+		return false
+	}
+	msg := strings.ToLower(syscallErr.Error())
+	return strings.Contains(msg, "broken pipe") || strings.Contains(msg, "connection reset by peer")
+}
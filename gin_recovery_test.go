@@ -0,0 +1,150 @@
+package logit
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGinRecovery(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	logfile := filepath.Join(t.TempDir(), "access.log")
+	app := gin.New()
+	app.Use(GinLoggerWithConfig(GinLoggerConfig{
+		TraceIDFunc: func(*gin.Context) string { return "trace-panic" },
+		OutputPaths: []string{logfile},
+	}), GinRecovery())
+	app.POST("/panic", func(c *gin.Context) {
+		GetGinRequestBody(c)
+		panic("oops")
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/panic", strings.NewReader(`{"k":"v"}`))
+	req.Header.Set("Content-Type", "application/json")
+	app.ServeHTTP(w, req)
+	if w.Code != http.StatusInternalServerError {
+		t.Error("invalid status code", w.Code)
+	}
+
+	logs := readTestLogs(t, logfile)
+	if len(logs) != 2 {
+		t.Fatal("invalid logs count", len(logs))
+	}
+	recovery, access := logs[0], logs[1]
+	if recovery["msg"] != "recovery from panic" || recovery["panic"] != "oops" {
+		t.Error("invalid recovery log", recovery)
+	}
+	if recovery["trace_id"] != "trace-panic" || recovery["request_body"] != `{"k":"v"}` {
+		t.Error("recovery log should contain trace id and request details", recovery)
+	}
+	if _, exists := recovery["stacktrace"]; !exists {
+		t.Error("recovery log should contain stacktrace")
+	}
+	if access["status_code"].(float64) != http.StatusInternalServerError || access["trace_id"] != "trace-panic" {
+		t.Error("invalid access log", access)
+	}
+}
+
+func TestGinRecoveryPartialBody(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	logfile := filepath.Join(t.TempDir(), "access.log")
+	counter := &countingReader{r: strings.NewReader(`{"k":"v"}`)}
+	readInRecovery := -1
+	app := gin.New()
+	app.Use(GinLoggerWithConfig(GinLoggerConfig{
+		EnableRequestBody: true,
+		OutputPaths:       []string{logfile},
+	}), GinRecoveryWithConfig(GinRecoveryConfig{
+		Handler: func(c *gin.Context, err interface{}) {
+			readInRecovery = counter.n
+			c.AbortWithStatus(http.StatusInternalServerError)
+		},
+	}))
+	app.POST("/panic", func(c *gin.Context) {
+		_, _ = c.Request.Body.Read(make([]byte, 3))
+		panic("oops")
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/panic", counter)
+	req.Header.Set("Content-Type", "application/json")
+	app.ServeHTTP(httptest.NewRecorder(), req)
+	// recovery 只记录 handler 已读取的 body ，不读取连接
+	if readInRecovery != 3 {
+		t.Error("recovery should not read the request body", readInRecovery)
+	}
+	logs := readTestLogs(t, logfile)
+	if len(logs) != 2 || logs[0]["request_body"] != `{"k` {
+		t.Error("recovery log should contain the captured body", logs)
+	}
+}
+
+func TestGinRecoveryClientIP(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	logfile := filepath.Join(t.TempDir(), "access.log")
+	app := gin.New()
+	app.Use(GinLoggerWithConfig(GinLoggerConfig{
+		TrustedProxies: []string{"10.0.0.0/8"},
+		OutputPaths:    []string{logfile},
+	}), GinRecovery())
+	app.GET("/panic", func(c *gin.Context) {
+		panic("oops")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/panic", nil)
+	req.RemoteAddr = "10.1.1.1:1234"
+	req.Header.Set("X-Forwarded-For", "1.1.1.1, 8.8.8.8")
+	app.ServeHTTP(httptest.NewRecorder(), req)
+	logs := readTestLogs(t, logfile)
+	if len(logs) != 2 {
+		t.Fatal("invalid logs count", len(logs))
+	}
+	// recovery 日志与访问日志使用相同的客户端 IP
+	if logs[0]["client_ip"] != "8.8.8.8" || logs[0]["client_ip"] != logs[1]["client_ip"] {
+		t.Error("recovery log should use the resolved client ip", logs[0]["client_ip"], logs[1]["client_ip"])
+	}
+}
+
+func TestGinRecoveryBrokenPipe(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	logfile := filepath.Join(t.TempDir(), "access.log")
+	handled := false
+	app := gin.New()
+	app.Use(GinLoggerWithConfig(GinLoggerConfig{OutputPaths: []string{logfile}}), GinRecoveryWithConfig(GinRecoveryConfig{
+		Handler: func(c *gin.Context, err interface{}) {
+			handled = true
+			c.AbortWithStatus(http.StatusServiceUnavailable)
+		},
+	}))
+	app.GET("/broken", func(c *gin.Context) {
+		panic(&net.OpError{Op: "write", Err: &os.SyscallError{Syscall: "write", Err: syscall.EPIPE}})
+	})
+	app.GET("/custom", func(c *gin.Context) {
+		panic("oops")
+	})
+
+	app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/broken", nil))
+	if handled {
+		t.Error("handler should not be called on broken pipe")
+	}
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/custom", nil))
+	if !handled || w.Code != http.StatusServiceUnavailable {
+		t.Error("custom handler should be called", w.Code)
+	}
+
+	logs := readTestLogs(t, logfile)
+	if logs[0]["msg"] != "recovery from broken pipe" {
+		t.Error("invalid broken pipe log", logs[0])
+	}
+	if _, exists := logs[0]["stacktrace"]; exists {
+		t.Error("broken pipe log should not contain stacktrace")
+	}
+}
@@ -0,0 +1,170 @@
+package logit
+
+import (
+	"math/rand"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// GinRouteRule 按路由覆盖 GinLoggerConfig 中的日志配置
+// 使用请求方法和路由模板（即 c.FullPath() ，如 /api/orders/:id ）匹配请求，未匹配的请求使用全局配置
+type GinRouteRule struct {
+	// 请求方法，为空时匹配全部方法
+	Method string
+	// 路由模板，与注册路由时的 path 一致
+	Path string
+	// 覆盖慢请求时间阈值，为 0 时使用全局配置
+	// Optional.
+	SlowThreshold time.Duration
+	// 覆盖是否打印请求头信息，为 nil 时使用全局配置
+	// Optional.
+	EnableRequestHeader *bool
+	// 覆盖是否打印请求体信息，为 nil 时使用全局配置
+	// Optional.
+	EnableRequestBody *bool
+	// 覆盖是否打印响应体信息，为 nil 时使用全局配置
+	// Optional.
+	EnableResponseBody *bool
+	// 请求成功（状态码 400 以下且不是慢请求）时使用的日志级别，为空时使用 info
+	// Optional.
+	SuccessLevel string
+	// 请求成功时的采样率，取值 (0, 1] ，如 0.01 表示只记录 1% 的成功请求，为 0 时使用全局采样配置
+	// Optional.
+	SampleRate float64
+	// 请求成功时每秒最多记录的请求数，为 0 时使用全局采样配置
+	// Optional.
+	SampleTokensPerSecond float64
+}
+
+// ginLogPolicy 单个请求生效的日志配置
+type ginLogPolicy struct {
+	slowThreshold       time.Duration
+	enableRequestHeader bool
+	enableRequestBody   bool
+	enableResponseBody  bool
+	successLevel        zapcore.Level
+	sampleRate          float64
+	tokensPerSecond     float64
+}
+
+// ginRouteRules 按 "METHOD path" 索引的路由规则
+type ginRouteRules map[string]GinRouteRule
+
+// ginRouteRuleKey
+//
+//	@Description: 生成路由规则索引的 key
+//	@param method
+//	@param fullPath
+//	@return string
+func ginRouteRuleKey(method, fullPath string) string {
+	return strings.ToUpper(method) + " " + fullPath
+}
+
+// newGinRouteRules
+//
+//	@Description: 校验并索引路由规则，配置错误时 panic
+//	@param rules
+//	@return ginRouteRules
+func newGinRouteRules(rules []GinRouteRule) ginRouteRules {
+	index := ginRouteRules{}
+	for _, rule := range rules {
+		if rule.Path == "" {
+			panic("gin route rule path is empty")
+		}
+		if rule.SuccessLevel != "" {
+			if _, exists := ZapcoreLevelMap[strings.ToLower(rule.SuccessLevel)]; !exists {
+				panic("gin route rule " + rule.Path + " invalid success level: " + rule.SuccessLevel)
+			}
+		}
+		if rule.SampleRate < 0 || rule.SampleRate > 1 {
+			panic("gin route rule " + rule.Path + " sample rate must be in [0, 1]")
+		}
+		if rule.SampleTokensPerSecond < 0 {
+			panic("gin route rule " + rule.Path + " sample tokens per second must not be negative")
+		}
+		index[ginRouteRuleKey(rule.Method, rule.Path)] = rule
+	}
+	return index
+}
+
+// match
+//
+//	@Description: 查找请求匹配的路由规则，精确匹配方法的规则优先
+//	@receiver r
+//	@param method
+//	@param fullPath
+//	@return GinRouteRule
+//	@return bool
+func (r ginRouteRules) match(method, fullPath string) (GinRouteRule, bool) {
+	if len(r) == 0 || fullPath == "" {
+		return GinRouteRule{}, false
+	}
+	if rule, exists := r[ginRouteRuleKey(method, fullPath)]; exists {
+		return rule, true
+	}
+	rule, exists := r[ginRouteRuleKey("", fullPath)]
+	return rule, exists
+}
+
+// policy
+//
+//	@Description: 根据全局配置和匹配的路由规则生成请求生效的日志配置
+//	@receiver r
+//	@param conf
+//	@param method
+//	@param fullPath
+//	@return ginLogPolicy
+func (r ginRouteRules) policy(conf GinLoggerConfig, method, fullPath string) ginLogPolicy {
+	p := ginLogPolicy{
+		slowThreshold:       conf.SlowThreshold,
+		enableRequestHeader: conf.EnableRequestHeader,
+		enableRequestBody:   conf.EnableRequestBody,
+		enableResponseBody:  conf.EnableResponseBody,
+		successLevel:        zap.InfoLevel,
+	}
+	if conf.Sampling != nil {
+		p.sampleRate = conf.Sampling.Rate
+		p.tokensPerSecond = conf.Sampling.TokensPerSecond
+	}
+	rule, exists := r.match(method, fullPath)
+	if !exists {
+		return p
+	}
+	if rule.SlowThreshold > 0 {
+		p.slowThreshold = rule.SlowThreshold
+	}
+	if rule.EnableRequestHeader != nil {
+		p.enableRequestHeader = *rule.EnableRequestHeader
+	}
+	if rule.EnableRequestBody != nil {
+		p.enableRequestBody = *rule.EnableRequestBody
+	}
+	if rule.EnableResponseBody != nil {
+		p.enableResponseBody = *rule.EnableResponseBody
+	}
+	if rule.SuccessLevel != "" {
+		p.successLevel = ZapcoreLevelMap[strings.ToLower(rule.SuccessLevel)]
+	}
+	if rule.SampleRate > 0 {
+		p.sampleRate = rule.SampleRate
+	}
+	if rule.SampleTokensPerSecond > 0 {
+		p.tokensPerSecond = rule.SampleTokensPerSecond
+	}
+	return p
+}
+
+// sampled
+//
+//	@Description: 按采样率判断成功请求是否被采样记录
+//	@receiver p
+//	@return bool
+func (p ginLogPolicy) sampled() bool {
+	if p.sampleRate <= 0 || p.sampleRate >= 1 {
+		return true
+	}
+	return rand.Float64() < p.sampleRate
+}
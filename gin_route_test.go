@@ -0,0 +1,100 @@
+package logit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func TestGinRouteRulesPolicy(t *testing.T) {
+	enable := true
+	conf := GinLoggerConfig{SlowThreshold: time.Second}
+	rules := newGinRouteRules([]GinRouteRule{
+		{Method: "post", Path: "/api/orders", EnableRequestBody: &enable, SlowThreshold: time.Millisecond},
+		{Path: "/api/orders", SuccessLevel: "debug"},
+		{Path: "/healthz", SampleRate: 0.01},
+	})
+
+	p := rules.policy(conf, http.MethodPost, "/api/orders")
+	if !p.enableRequestBody || p.slowThreshold != time.Millisecond || p.successLevel != zap.InfoLevel {
+		t.Error("method rule should take precedence", p)
+	}
+	p = rules.policy(conf, http.MethodGet, "/api/orders")
+	if p.enableRequestBody || p.slowThreshold != time.Second || p.successLevel != zap.DebugLevel {
+		t.Error("any method rule should match", p)
+	}
+	p = rules.policy(conf, http.MethodGet, "/healthz")
+	if p.sampleRate != 0.01 {
+		t.Error("invalid sample rate", p)
+	}
+	p = rules.policy(conf, http.MethodGet, "/other")
+	if p.enableRequestBody || p.slowThreshold != time.Second || p.sampleRate != 0 || !p.sampled() {
+		t.Error("unmatched route should use global config", p)
+	}
+}
+
+func TestGinRouteRulesInvalid(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("invalid success level should panic")
+		}
+	}()
+	newGinRouteRules([]GinRouteRule{{Path: "/", SuccessLevel: "verbose"}})
+}
+
+func TestGinLoggerRouteRules(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	logfile := filepath.Join(t.TempDir(), "access.log")
+	enable := true
+	app := gin.New()
+	app.Use(GinLoggerWithConfig(GinLoggerConfig{
+		OutputPaths: []string{logfile},
+		RouteRules: []GinRouteRule{
+			{Method: http.MethodPost, Path: "/api/orders", EnableRequestBody: &enable},
+			{Path: "/healthz", SampleRate: 0.000001},
+		},
+	}))
+	app.POST("/api/orders", func(c *gin.Context) {
+		c.Status(201)
+	})
+	app.POST("/api/users", func(c *gin.Context) {
+		c.Status(201)
+	})
+	app.GET("/healthz", func(c *gin.Context) {
+		if c.Query("fail") != "" {
+			c.Status(503)
+			return
+		}
+		c.Status(200)
+	})
+
+	for _, path := range []string{"/api/orders", "/api/users"} {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"k":"v"}`))
+		req.Header.Set("Content-Type", "application/json")
+		app.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	for i := 0; i < 10; i++ {
+		app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	}
+	app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz?fail=1", nil))
+
+	logs := readTestLogs(t, logfile)
+	if len(logs) != 3 {
+		t.Fatal("successful /healthz requests should be sampled out, logs count:", len(logs))
+	}
+	if logs[0]["request_body"] != `{"k":"v"}` {
+		t.Error("request body should be logged for POST /api/orders", logs[0])
+	}
+	if _, exists := logs[1]["request_body"]; exists {
+		t.Error("request body should not be logged for POST /api/users", logs[1])
+	}
+	if logs[2]["status_code"].(float64) != 503 {
+		t.Error("failed request should always be logged", logs[2])
+	}
+}
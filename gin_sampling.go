@@ -0,0 +1,200 @@
+package logit
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// 默认采样汇总日志输出间隔 1 分钟
+	defaultGinSamplingSummaryInterval = time.Minute
+)
+
+// GinSamplingConfig 访问日志采样配置
+// 只对成功请求（状态码 400 以下、没有 c.Errors 且不是慢请求）采样，失败请求和慢请求始终记录
+// Rate 和 TokensPerSecond 同时设置时需同时满足才记录
+type GinSamplingConfig struct {
+	// 固定采样率，取值 (0, 1] ，如 0.1 表示只记录 10% 的成功请求，为 0 时不按比例采样
+	// 可以通过 GinRouteRule.SampleRate 按路由覆盖
+	// Optional.
+	Rate float64
+	// 每个路由每秒最多记录的成功请求数，为 0 时不限制
+	// 可以通过 GinRouteRule.SampleTokensPerSecond 按路由覆盖
+	// Optional.
+	TokensPerSecond float64
+	// 令牌桶容量，允许的瞬时突发记录数，默认等于 TokensPerSecond （最小为 1）
+	// Optional.
+	Burst int
+	// 输出采样汇总日志的间隔，汇总日志记录间隔内每个路由被丢弃的请求数，默认 1 分钟
+	// Optional.
+	SummaryInterval time.Duration
+}
+
+// ginTokenBucket 单个路由的令牌桶
+type ginTokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// ginSampler 访问日志采样器，记录每个路由被采样丢弃的请求数
+// 有请求被丢弃时使用定时器按间隔输出汇总，不依赖之后的请求，没有请求被丢弃时不占用定时器
+type ginSampler struct {
+	burst    int
+	interval time.Duration
+	// 输出汇总日志的方法，为 nil 时不自动输出
+	report func(fields []zap.Field)
+
+	mu          sync.Mutex
+	buckets     map[string]*ginTokenBucket
+	sampledOut  map[string]int64
+	lastSummary time.Time
+	// 是否已经安排了汇总日志的输出
+	scheduled bool
+}
+
+// newGinSampler
+//
+//	@Description: 创建访问日志采样器
+//	@param conf 为 nil 时使用默认配置，只处理路由规则中的采样
+//	@param report 输出汇总日志的方法，为 nil 时不自动输出
+//	@return *ginSampler
+func newGinSampler(conf *GinSamplingConfig, report func(fields []zap.Field)) *ginSampler {
+	s := &ginSampler{
+		interval:    defaultGinSamplingSummaryInterval,
+		report:      report,
+		buckets:     map[string]*ginTokenBucket{},
+		sampledOut:  map[string]int64{},
+		lastSummary: time.Now(),
+	}
+	if conf == nil {
+		return s
+	}
+	if conf.Rate < 0 || conf.Rate > 1 {
+		panic("gin sampling rate must be in [0, 1]")
+	}
+	if conf.TokensPerSecond < 0 {
+		panic("gin sampling tokens per second must not be negative")
+	}
+	s.burst = conf.Burst
+	if conf.SummaryInterval > 0 {
+		s.interval = conf.SummaryInterval
+	}
+	return s
+}
+
+// sample
+//
+//	@Description: 判断成功请求是否记录，不记录时计入被丢弃的请求数
+//	@receiver s
+//	@param route 路由，如 "GET /api/orders/:id"
+//	@param p 请求生效的日志配置
+//	@param now
+//	@return bool
+func (s *ginSampler) sample(route string, p ginLogPolicy, now time.Time) bool {
+	keep := p.sampled()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if keep && p.tokensPerSecond > 0 {
+		keep = s.take(route, p.tokensPerSecond, now)
+	}
+	if !keep {
+		s.sampledOut[route]++
+		s.schedule(now)
+	}
+	return keep
+}
+
+// schedule
+//
+//	@Description: 安排在距离上次汇总一个间隔后输出汇总日志，已经安排过时不重复安排，调用方需持有锁
+//	@receiver s
+//	@param now
+func (s *ginSampler) schedule(now time.Time) {
+	if s.report == nil || s.scheduled {
+		return
+	}
+	s.scheduled = true
+	time.AfterFunc(s.lastSummary.Add(s.interval).Sub(now), s.flush)
+}
+
+// flush
+//
+//	@Description: 定时器触发时输出汇总日志，仍有未汇总的丢弃请求时继续安排下一次输出
+//	@receiver s
+func (s *ginSampler) flush() {
+	if fields, ok := s.summary(time.Now()); ok {
+		s.report(fields)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scheduled = false
+	if len(s.sampledOut) > 0 {
+		s.schedule(time.Now())
+	}
+}
+
+// take
+//
+//	@Description: 从路由的令牌桶中取一个令牌，调用方需持有锁
+//	@receiver s
+//	@param route
+//	@param tokensPerSecond
+//	@param now
+//	@return bool
+func (s *ginSampler) take(route string, tokensPerSecond float64, now time.Time) bool {
+	burst := float64(s.burst)
+	if burst <= 0 {
+		burst = tokensPerSecond
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	b, exists := s.buckets[route]
+	if !exists {
+		b = &ginTokenBucket{tokens: burst, last: now}
+		s.buckets[route] = b
+	}
+	b.tokens += now.Sub(b.last).Seconds() * tokensPerSecond
+	if b.tokens > burst {
+		b.tokens = burst
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// summary
+//
+//	@Description: 距离上次汇总超过间隔时返回汇总字段并重置计数，间隔内没有请求被丢弃时不返回
+//	@receiver s
+//	@param now
+//	@return []zap.Field
+//	@return bool
+func (s *ginSampler) summary(now time.Time) ([]zap.Field, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	elapsed := now.Sub(s.lastSummary)
+	if elapsed < s.interval {
+		return nil, false
+	}
+	s.lastSummary = now
+	if len(s.sampledOut) == 0 {
+		return nil, false
+	}
+	routes := s.sampledOut
+	s.sampledOut = map[string]int64{}
+	var total int64
+	for _, n := range routes {
+		total += n
+	}
+	return []zap.Field{
+		zap.Int64("sampled_out", total),
+		zap.Any("sampled_out_routes", routes),
+		zap.Float64("interval_seconds", elapsed.Seconds()),
+	}, true
+}
@@ -0,0 +1,127 @@
+package logit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGinSamplerTokenBucket(t *testing.T) {
+	s := newGinSampler(&GinSamplingConfig{TokensPerSecond: 2}, nil)
+	p := ginLogPolicy{tokensPerSecond: 2}
+	now := time.Now()
+	kept := 0
+	for i := 0; i < 5; i++ {
+		if s.sample("GET /", p, now) {
+			kept++
+		}
+	}
+	if kept != 2 {
+		t.Error("burst should default to tokens per second, kept:", kept)
+	}
+	if !s.sample("GET /", p, now.Add(500*time.Millisecond)) {
+		t.Error("token should be refilled after 500ms")
+	}
+	if !s.sample("GET /other", p, now) {
+		t.Error("each route should have its own bucket")
+	}
+	if s.sampledOut["GET /"] != 3 {
+		t.Error("invalid sampled out count", s.sampledOut)
+	}
+}
+
+func TestGinSamplerSummary(t *testing.T) {
+	s := newGinSampler(&GinSamplingConfig{Rate: 0.000001, SummaryInterval: time.Minute}, nil)
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		s.sample("GET /healthz", ginLogPolicy{sampleRate: 0.000001}, now)
+	}
+	if _, ok := s.summary(now); ok {
+		t.Error("summary should not be emitted before interval")
+	}
+	fields, ok := s.summary(now.Add(2 * time.Minute))
+	if !ok || fields[0].Integer != 3 {
+		t.Error("invalid summary", fields)
+	}
+	if _, ok := s.summary(now.Add(4 * time.Minute)); ok {
+		t.Error("summary should not be emitted when nothing sampled out")
+	}
+}
+
+func TestGinLoggerSampling(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	logfile := filepath.Join(t.TempDir(), "access.log")
+	app := gin.New()
+	app.Use(GinLoggerWithConfig(GinLoggerConfig{
+		OutputPaths:   []string{logfile},
+		SlowThreshold: 10 * time.Millisecond,
+		Sampling:      &GinSamplingConfig{TokensPerSecond: 0.001, Burst: 1, SummaryInterval: time.Hour},
+	}))
+	app.GET("/ok", func(c *gin.Context) {
+		c.Status(200)
+	})
+	app.GET("/slow", func(c *gin.Context) {
+		time.Sleep(20 * time.Millisecond)
+		c.Status(200)
+	})
+	app.GET("/error", func(c *gin.Context) {
+		c.Error(http.ErrBodyNotAllowed)
+		c.Status(200)
+	})
+	app.GET("/notfound", func(c *gin.Context) {
+		c.Status(404)
+	})
+
+	for i := 0; i < 5; i++ {
+		app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ok", nil))
+	}
+	for _, path := range []string{"/slow", "/slow", "/error", "/error", "/notfound", "/notfound"} {
+		app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	logs := readTestLogs(t, logfile)
+	if len(logs) != 7 {
+		t.Fatal("only the first /ok request should be kept, logs count:", len(logs))
+	}
+	if logs[0]["path"] != "/ok" {
+		t.Error("invalid first log", logs[0])
+	}
+	for _, l := range logs[1:] {
+		if l["path"] == "/ok" {
+			t.Error("successful request should be sampled out", l)
+		}
+	}
+}
+
+func TestGinLoggerSamplingSummary(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	logfile := filepath.Join(t.TempDir(), "access.log")
+	app := gin.New()
+	app.Use(GinLoggerWithConfig(GinLoggerConfig{
+		OutputPaths: []string{logfile},
+		Sampling:    &GinSamplingConfig{TokensPerSecond: 0.001, Burst: 1, SummaryInterval: 20 * time.Millisecond},
+	}))
+	app.GET("/ok", func(c *gin.Context) {
+		c.Status(200)
+	})
+	// 突发请求之后没有新的请求，汇总日志仍然按间隔输出
+	for i := 0; i < 5; i++ {
+		app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ok", nil))
+	}
+
+	var summary map[string]interface{}
+	for deadline := time.Now().Add(time.Second); summary == nil && time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		for _, l := range readTestLogs(t, logfile) {
+			if l["msg"] == "access log sampling summary" {
+				summary = l
+			}
+		}
+	}
+	if summary == nil || summary["sampled_out"] != float64(4) {
+		t.Error("sampling summary should be emitted without later requests", summary)
+	}
+}
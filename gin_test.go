@@ -5,6 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -57,3 +62,55 @@ func TestGinLoggerWithConfig(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func concurrentA(c *gin.Context) {
+	c.String(200, c.Param("id"))
+}
+
+func concurrentB(c *gin.Context) {
+	c.String(404, c.Param("id"))
+}
+
+func TestGinLoggerConcurrent(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	app := gin.New()
+	app.Use(GinLoggerWithConfig(GinLoggerConfig{
+		EnableResponseBody: true,
+		TraceIDFunc:        func(c *gin.Context) string { return c.GetHeader("X-Request-Id") },
+		// Formatter 收到的扩展信息必须与当前请求一致
+		Formatter: func(c *gin.Context, ext GinLogExtends) string {
+			id := c.Param("id")
+			if ext.TraceID != "trace-"+id || ext.Path != c.Request.URL.Path || ext.RequestURI != c.Request.RequestURI {
+				t.Errorf("request %s got ext %+v", id, ext)
+			}
+			wantHandle, wantStatus := "logit.concurrentA", 200
+			if strings.HasPrefix(ext.Path, "/b/") {
+				wantHandle, wantStatus = "logit.concurrentB", 404
+			}
+			if ext.HandleName != wantHandle || ext.StatusCode != wantStatus || ext.BodySize != len(id) {
+				t.Errorf("request %s got ext %+v", id, ext)
+			}
+			return ext.Method + " " + ext.Path
+		},
+		OutputPaths: []string{filepath.Join(t.TempDir(), "access.log")},
+	}))
+	app.GET("/a/:id", concurrentA)
+	app.GET("/b/:id", concurrentB)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := strconv.Itoa(i)
+			target := "/a/" + id
+			if i%2 == 1 {
+				target = "/b/" + id
+			}
+			req := httptest.NewRequest(http.MethodGet, target, nil)
+			req.Header.Set("X-Request-Id", "trace-"+id)
+			app.ServeHTTP(httptest.NewRecorder(), req)
+		}(i)
+	}
+	wg.Wait()
+}
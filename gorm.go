@@ -19,13 +19,15 @@ const (
 	GormLoggerName = "gorm"
 	// GormLoggerCallerSkip caller skip
 	GormLoggerCallerSkip = 3
+	// gormSilentLevel gormlogger.Silent 对应的日志级别，高于全部日志级别，不打印任何日志
+	gormSilentLevel = zapcore.FatalLevel + 1
 )
 
 type GormLoggerOptions struct {
 	Name string
 	// 日志级别
 	LogLevel zapcore.Level
-	// CallerSkip，默认值 3
+	// CallerSkip，默认跳过 gorm 和 gorm logger 的栈帧，从调用 gorm 的应用代码中获取 caller
 	CallerSkip int
 	// 慢请求时间阈值 请求处理时间超过该值则使用 Warn 级别打印日志
 	SlowThreshold time.Duration
@@ -46,6 +48,46 @@ type GormLoggerOptions struct {
 	EncoderConfig *zapcore.EncoderConfig
 	// RecordNotFoundErr 错误等级
 	RecordNotFoundErrLevel string
+	// 是否打印带占位符的 sql 模板，参数值单独记录在 vars 字段中
+	// 需要通过 db.Use(logger) 注册插件才能获取参数值，未注册时只记录 sql 模板
+	// Optional.
+	ParameterizedSQL bool
+	// 处理 vars 字段中的参数值，如对手机号等敏感信息脱敏，返回值替换原参数值记录到日志中
+	// Optional.
+	VarsRedactor func(ctx context.Context, sql string, vars []interface{}) []interface{}
+	// 是否记录 sql 查询结构的指纹 fingerprint 字段，相同结构不同参数的 sql 指纹相同
+	// Optional.
+	EnableFingerprint bool
+	// 是否对慢查询的 SELECT 语句在新的连接上异步执行 EXPLAIN ，以相同的 trace id 记录执行计划
+	// 支持 sqlite 、 mysql 、 postgres ，需要通过 db.Use(logger) 注册插件
+	// Optional.
+	EnableExplain bool
+	// 同一查询结构执行 EXPLAIN 的最小间隔，默认 1 分钟
+	// Optional.
+	ExplainInterval time.Duration
+	// 执行 EXPLAIN 的超时时间，默认 5 秒
+	// Optional.
+	ExplainTimeout time.Duration
+	// 同一请求中相同结构的 sql 执行次数超过该值时打印 Warn 日志，用于发现 N+1 查询，为 0 时不检测
+	// 请求的 context 需要经过 GinLogger 或 WithQueryStats
+	// Optional.
+	RepeatedQueryThreshold int
+	// 同一请求中 sql 执行次数超过该值时打印 Warn 日志，为 0 时不检测
+	// 请求的 context 需要经过 GinLogger 或 WithQueryStats
+	// Optional.
+	QueryBudget int
+	// gorm 默认 logger 的配置，设置后覆盖对应的配置项：
+	// LogLevel 覆盖 LogLevel ， SlowThreshold 覆盖 SlowThreshold ， ParameterizedQueries 开启 ParameterizedSQL ，
+	// IgnoreRecordNotFoundError 不记录 gorm.ErrRecordNotFound 错误， Colorful 使用带颜色级别的 console 格式输出
+	// Optional.
+	GormConfig *gormlogger.Config
+}
+
+// gormSQLOptions sql 字段相关的配置
+type gormSQLOptions struct {
+	parameterized bool
+	varsRedactor  func(ctx context.Context, sql string, vars []interface{}) []interface{}
+	fingerprint   bool
 }
 
 // GormLogger 使用 zap 来打印 gorm 的日志
@@ -59,12 +101,24 @@ type GormLogger struct {
 	slowThreshold          time.Duration
 	_logger                *zap.Logger
 	recordNotFoundErrLevel string
+	// 是否不记录 gorm.ErrRecordNotFound 错误
+	ignoreRecordNotFoundError bool
+	sqlOptions                *gormSQLOptions
+	// 是否动态计算 caller skip
+	dynamicCaller bool
+	// 慢查询 EXPLAIN 执行器，未开启时为 nil
+	explainer *gormExplainer
+	// 请求中相同结构 sql 执行次数的告警阈值
+	repeatedQueryThreshold int
+	// 请求中 sql 执行次数的告警阈值
+	queryBudget int
 }
 
 var gormLogLevelMap = map[gormlogger.LogLevel]zapcore.Level{
-	gormlogger.Info:  zap.InfoLevel,
-	gormlogger.Warn:  zap.WarnLevel,
-	gormlogger.Error: zap.ErrorLevel,
+	gormlogger.Silent: gormSilentLevel,
+	gormlogger.Info:   zap.InfoLevel,
+	gormlogger.Warn:   zap.WarnLevel,
+	gormlogger.Error:  zap.ErrorLevel,
 }
 
 // LogMode 实现 gorm baseLogger 接口方法
@@ -84,36 +138,138 @@ func (g GormLogger) CtxLogger(ctx context.Context) *zap.Logger {
 	return ctxLogger.WithOptions(zap.AddCallerSkip(g.callerSkip))
 }
 
+// callerLogger
+//
+//	@Description: 创建 caller 为应用代码的 ctx logger ，只能在 GormLogger 实现 gorm 接口的方法中直接调用
+//	@receiver g
+//	@param ctx
+//	@return *zap.Logger
+func (g GormLogger) callerLogger(ctx context.Context) *zap.Logger {
+	if !g.dynamicCaller {
+		return g.CtxLogger(ctx)
+	}
+	skip, ok := gormCallerSkip(1)
+	if !ok {
+		return g.CtxLogger(ctx)
+	}
+	_, ctxLogger := NewCtxLogger(ctx, g._logger, "")
+	return ctxLogger.WithOptions(zap.AddCallerSkip(skip))
+}
+
 // Info 实现 gorm baseLogger 接口方法
 func (g GormLogger) Info(ctx context.Context, msg string, data ...interface{}) {
 	if g.logLevel <= zap.InfoLevel {
-		g.CtxLogger(ctx).Sugar().Infof(msg, data...)
+		g.callerLogger(ctx).Sugar().Infof(msg, data...)
 	}
 }
 
 // Warn 实现 gorm baseLogger 接口方法
 func (g GormLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
 	if g.logLevel <= zap.WarnLevel {
-		g.CtxLogger(ctx).Sugar().Warnf(msg, data...)
+		g.callerLogger(ctx).Sugar().Warnf(msg, data...)
 	}
 }
 
 // Error 实现 gorm baseLogger 接口方法
 func (g GormLogger) Error(ctx context.Context, msg string, data ...interface{}) {
 	if g.logLevel <= zap.ErrorLevel {
-		g.CtxLogger(ctx).Sugar().Errorf(msg, data...)
+		g.callerLogger(ctx).Sugar().Errorf(msg, data...)
+	}
+}
+
+// ParamsFilter 实现 gorm ParamsFilter 接口方法，打印 sql 模板时不填充参数
+func (g GormLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	if g.sqlOptions != nil && g.sqlOptions.parameterized {
+		return sql, nil
+	}
+	return sql, params
+}
+
+// sqlFields
+//
+//	@Description: 生成 sql 相关的字段，注册了插件时记录 table 、 operation 、 model 和 tx_id
+//	@receiver g
+//	@param ctx
+//	@param sql
+//	@return []zap.Field
+func (g GormLogger) sqlFields(ctx context.Context, sql string) []zap.Field {
+	fields := []zap.Field{zap.String("sql", sql)}
+	info := gormStatement(ctx)
+	if info != nil {
+		fields = append(fields, info.fields()...)
+	}
+	if g.sqlOptions == nil {
+		return fields
+	}
+	if g.sqlOptions.parameterized {
+		if info != nil {
+			vars := append([]interface{}(nil), info.stmt.Vars...)
+			if g.sqlOptions.varsRedactor != nil {
+				vars = g.sqlOptions.varsRedactor(ctx, sql, vars)
+			}
+			fields = append(fields, zap.Any("vars", vars))
+		}
+	}
+	if g.sqlOptions.fingerprint {
+		fields = append(fields, zap.String("fingerprint", SQLFingerprint(sql)))
+	}
+	return fields
+}
+
+// recordQueryStats
+//
+//	@Description: 记录请求中的 sql 执行次数和耗时，相同结构的 sql 重复执行或执行次数超出预算时打印 Warn 日志，每个请求只打印一次
+//	@receiver g
+//	@param logger
+//	@param stats
+//	@param sql
+//	@param latency
+func (g GormLogger) recordQueryStats(logger *zap.Logger, stats *QueryStats, sql string, latency time.Duration) {
+	fingerprint := ""
+	if g.repeatedQueryThreshold > 0 {
+		fingerprint = SQLFingerprint(sql)
+	}
+	queries, repeated := stats.record(fingerprint, latency)
+	if g.logLevel > zap.WarnLevel {
+		return
+	}
+	if g.repeatedQueryThreshold > 0 && repeated == g.repeatedQueryThreshold+1 {
+		logger.Warn("sql repeated in request",
+			zap.String("sql", sql),
+			zap.String("fingerprint", fingerprint),
+			zap.Int("repeated", repeated),
+			zap.Int("threshold", g.repeatedQueryThreshold),
+		)
+	}
+	if g.queryBudget > 0 && queries == int64(g.queryBudget)+1 {
+		logger.Warn("sql queries exceed budget",
+			zap.Int64("queries", queries),
+			zap.Int("budget", g.queryBudget),
+		)
 	}
 }
 
 // Trace 实现 gorm baseLogger 接口方法
 func (g GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	stats := CtxQueryStats(ctx)
+	// Silent 时只记录请求中的 sql 统计
+	if g.logLevel > zap.ErrorLevel && stats == nil {
+		return
+	}
 	now := time.Now()
 	latency := now.Sub(begin).Seconds()
 	sql, rows := fc()
 	sql = goutils.RemoveDuplicateWhitespace(sql, true)
-	l := g.CtxLogger(ctx).Named("sql")
+	// explain 日志使用同一个 logger ，保证 trace id 相同
+	sqlLogger := g.callerLogger(ctx).Named("sql")
+	l := sqlLogger.With(g.sqlFields(ctx, sql)...)
+	if stats != nil {
+		g.recordQueryStats(sqlLogger, stats, sql, now.Sub(begin))
+	}
 	switch {
-	case err != nil:
+	case g.logLevel > zap.ErrorLevel:
+		return
+	case err != nil && !(g.ignoreRecordNotFoundError && errors.Is(err, gorm.ErrRecordNotFound)):
 		level := zap.ErrorLevel
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			var err1 error
@@ -121,11 +277,14 @@ func (g GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (strin
 				level = zap.ErrorLevel
 			}
 		}
-		l.Log(level, "sql trace", zap.String("sql", sql), zap.Float64("latency", latency), zap.Int64("rows", rows), zap.String("error", err.Error()))
-	case g.slowThreshold != 0 && latency > g.slowThreshold.Seconds():
-		l.Warn("sql trace[slow]", zap.String("sql", sql), zap.Float64("latency", latency), zap.Int64("rows", rows), zap.Float64("threshold", g.slowThreshold.Seconds()))
-	default:
-		l.Info("sql trace", zap.String("sql", sql), zap.Float64("latency", latency), zap.Int64("rows", rows))
+		l.Log(level, "sql trace", zap.Float64("latency", latency), zap.Int64("rows", rows), zap.String("error", err.Error()))
+	case g.slowThreshold != 0 && latency > g.slowThreshold.Seconds() && g.logLevel <= zap.WarnLevel:
+		l.Warn("sql trace[slow]", zap.Float64("latency", latency), zap.Int64("rows", rows), zap.Float64("threshold", g.slowThreshold.Seconds()))
+		if g.explainer != nil {
+			g.explainer.explain(sqlLogger, gormStatement(ctx))
+		}
+	case g.logLevel <= zap.InfoLevel:
+		l.Info("sql trace", zap.Float64("latency", latency), zap.Int64("rows", rows))
 	}
 }
 
@@ -136,23 +295,62 @@ func (g GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (strin
 //	@return GormLogger
 //	@return error
 func NewGormLogger(opt GormLoggerOptions) (GormLogger, error) {
+	format := "json"
+	// 使用 gorm 默认 logger 的配置覆盖对应的配置项
+	if conf := opt.GormConfig; conf != nil {
+		if level, exists := gormLogLevelMap[conf.LogLevel]; exists {
+			opt.LogLevel = level
+		}
+		if conf.SlowThreshold > 0 {
+			opt.SlowThreshold = conf.SlowThreshold
+		}
+		if conf.ParameterizedQueries {
+			opt.ParameterizedSQL = true
+		}
+		if conf.Colorful {
+			format = "console"
+			encoderConfig := defaultEncoderConfig
+			if opt.EncoderConfig != nil {
+				encoderConfig = *opt.EncoderConfig
+			}
+			encoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+			opt.EncoderConfig = &encoderConfig
+		}
+	}
 	l := GormLogger{
 		name:                   GormLoggerName,
 		callerSkip:             GormLoggerCallerSkip,
 		logLevel:               opt.LogLevel,
 		slowThreshold:          opt.SlowThreshold,
 		recordNotFoundErrLevel: opt.RecordNotFoundErrLevel,
+		repeatedQueryThreshold: opt.RepeatedQueryThreshold,
+		queryBudget:            opt.QueryBudget,
+	}
+	if opt.GormConfig != nil {
+		l.ignoreRecordNotFoundError = opt.GormConfig.IgnoreRecordNotFoundError
+	}
+	if opt.ParameterizedSQL || opt.EnableFingerprint {
+		l.sqlOptions = &gormSQLOptions{
+			parameterized: opt.ParameterizedSQL,
+			varsRedactor:  opt.VarsRedactor,
+			fingerprint:   opt.EnableFingerprint,
+		}
+	}
+	if opt.EnableExplain {
+		l.explainer = newGormExplainer(opt.ExplainInterval, opt.ExplainTimeout)
 	}
 	if opt.Name != "" {
 		l.name = opt.Name
 	}
 	if opt.CallerSkip != 0 {
 		l.callerSkip = opt.CallerSkip
+	} else {
+		l.dynamicCaller = true
 	}
 	var err error
 	l._logger, err = NewLogger(Options{
 		Level:             "debug",
-		Format:            "json",
+		Format:            format,
 		OutputPaths:       opt.OutputPaths,
 		InitialFields:     opt.InitialFields,
 		DisableCaller:     opt.DisableCaller,
@@ -162,3 +360,13 @@ func NewGormLogger(opt GormLoggerOptions) (GormLogger, error) {
 	l._logger = l._logger.Named(l.name)
 	return l, err
 }
+
+// NewGormLoggerWithConfig
+//
+//	@Description: 使用 gorm 默认 logger 的配置创建 logger ，可以直接替换 gormlogger.New(writer, config)
+//	@param config
+//	@return GormLogger
+//	@return error
+func NewGormLoggerWithConfig(config gormlogger.Config) (GormLogger, error) {
+	return NewGormLogger(GormLoggerOptions{GormConfig: &config})
+}
@@ -0,0 +1,190 @@
+package logit
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// 默认同一查询结构执行 EXPLAIN 的最小间隔
+	defaultGormExplainInterval = time.Minute
+	// 默认执行 EXPLAIN 的超时时间
+	defaultGormExplainTimeout = 5 * time.Second
+	// 同时执行的 EXPLAIN 数量上限，超出时跳过
+	gormExplainConcurrency = 2
+	// 记录的查询结构数量超过该值时清理过期记录
+	gormExplainMaxFingerprints = 1000
+)
+
+// 各数据库查看执行计划的语句前缀
+var gormExplainPrefixes = map[string]string{
+	"sqlite":   "EXPLAIN QUERY PLAN ",
+	"mysql":    "EXPLAIN ",
+	"postgres": "EXPLAIN ",
+}
+
+// gormExplainer 对慢查询异步执行 EXPLAIN ，按查询结构限制执行频率
+type gormExplainer struct {
+	interval time.Duration
+	timeout  time.Duration
+	// 限制同时执行的 EXPLAIN 数量
+	sem chan struct{}
+	wg  sync.WaitGroup
+
+	mu   sync.Mutex
+	last map[string]time.Time
+}
+
+// newGormExplainer
+//
+//	@Description: 创建慢查询 EXPLAIN 执行器
+//	@param interval 同一查询结构执行 EXPLAIN 的最小间隔，为 0 时使用默认值
+//	@param timeout 执行 EXPLAIN 的超时时间，为 0 时使用默认值
+//	@return *gormExplainer
+func newGormExplainer(interval, timeout time.Duration) *gormExplainer {
+	if interval <= 0 {
+		interval = defaultGormExplainInterval
+	}
+	if timeout <= 0 {
+		timeout = defaultGormExplainTimeout
+	}
+	return &gormExplainer{
+		interval: interval,
+		timeout:  timeout,
+		sem:      make(chan struct{}, gormExplainConcurrency),
+		last:     map[string]time.Time{},
+	}
+}
+
+// allow
+//
+//	@Description: 判断查询结构是否可以执行 EXPLAIN ，可以执行时记录执行时间
+//	@receiver e
+//	@param fingerprint
+//	@param now
+//	@return bool
+func (e *gormExplainer) allow(fingerprint string, now time.Time) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if last, exists := e.last[fingerprint]; exists && now.Sub(last) < e.interval {
+		return false
+	}
+	if len(e.last) >= gormExplainMaxFingerprints {
+		for fp, last := range e.last {
+			if now.Sub(last) >= e.interval {
+				delete(e.last, fp)
+			}
+		}
+	}
+	e.last[fingerprint] = now
+	return true
+}
+
+// acquire
+//
+//	@Description: 获取执行 EXPLAIN 的名额，先占用并发名额再按查询结构限流，并发已满时不记录执行时间
+//	@receiver e
+//	@param fingerprint
+//	@param now
+//	@return bool 返回 true 时执行完成后需要释放 e.sem
+func (e *gormExplainer) acquire(fingerprint string, now time.Time) bool {
+	select {
+	case e.sem <- struct{}{}:
+	default:
+		return false
+	}
+	if !e.allow(fingerprint, now) {
+		<-e.sem
+		return false
+	}
+	return true
+}
+
+// explain
+//
+//	@Description: 慢查询是 SELECT 语句时在新的连接上异步执行 EXPLAIN ，使用慢查询日志的 logger 记录执行计划
+//	需要注册插件获取 statement ，不支持的数据库、非 SELECT 语句和被限流的查询直接跳过
+//	@receiver e
+//	@param logger 带有 trace id 的 sql logger
+//	@param info
+func (e *gormExplainer) explain(logger *zap.Logger, info *gormStatementInfo) {
+	if info == nil || info.operation != "query" {
+		return
+	}
+	sql := strings.TrimSpace(info.stmt.SQL.String())
+	if !strings.HasPrefix(strings.ToLower(sql), "select") {
+		return
+	}
+	db := info.stmt.DB
+	prefix, supported := gormExplainPrefixes[db.Dialector.Name()]
+	if !supported || db.Config.ConnPool == nil {
+		return
+	}
+	fingerprint := SQLFingerprint(sql)
+	if !e.acquire(fingerprint, time.Now()) {
+		return
+	}
+	// 执行时 statement 会被重置，需要先复制参数
+	vars := append([]interface{}(nil), info.stmt.Vars...)
+	// 使用 db 初始的连接池，不占用事务中的连接
+	pool := db.Config.ConnPool
+	logger = logger.WithOptions(zap.WithCaller(false)).With(zap.String("sql", sql), zap.String("fingerprint", fingerprint))
+	e.wg.Add(1)
+	go func() {
+		defer func() {
+			<-e.sem
+			e.wg.Done()
+		}()
+		ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+		defer cancel()
+		rows, err := pool.QueryContext(ctx, prefix+sql, vars...)
+		if err != nil {
+			logger.Warn("sql explain failed", zap.Error(err))
+			return
+		}
+		defer rows.Close()
+		columns, err := rows.Columns()
+		if err != nil {
+			logger.Warn("sql explain failed", zap.Error(err))
+			return
+		}
+		plan := []map[string]interface{}{}
+		for rows.Next() {
+			values := make([]interface{}, len(columns))
+			pointers := make([]interface{}, len(columns))
+			for i := range values {
+				pointers[i] = &values[i]
+			}
+			if err := rows.Scan(pointers...); err != nil {
+				logger.Warn("sql explain failed", zap.Error(err))
+				return
+			}
+			row := make(map[string]interface{}, len(columns))
+			for i, column := range columns {
+				if b, ok := values[i].([]byte); ok {
+					row[column] = string(b)
+				} else {
+					row[column] = values[i]
+				}
+			}
+			plan = append(plan, row)
+		}
+		if err := rows.Err(); err != nil {
+			logger.Warn("sql explain failed", zap.Error(err))
+			return
+		}
+		logger.Info("sql explain", zap.Any("explain", plan))
+	}()
+}
+
+// wait
+//
+//	@Description: 等待正在执行的 EXPLAIN 完成
+//	@receiver e
+func (e *gormExplainer) wait() {
+	e.wg.Wait()
+}
@@ -0,0 +1,105 @@
+package logit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestGormLoggerExplain(t *testing.T) {
+	logfile := filepath.Join(t.TempDir(), "gorm.log")
+	logger, err := NewGormLogger(GormLoggerOptions{
+		OutputPaths:   []string{logfile},
+		SlowThreshold: time.Nanosecond,
+		EnableExplain: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "sqlite3.db")), &gorm.Config{Logger: logger})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(logger); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&Product{}); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.WithValue(context.Background(), TraceIDKeyName, "trace-explain")
+	var ret []Product
+	db.WithContext(ctx).Where("code = ?", "a").Find(&ret)
+	// 相同结构的查询被限流
+	db.WithContext(ctx).Where("code = ?", "b").Find(&ret)
+	db.WithContext(ctx).Create(&Product{Code: "c"})
+	logger.explainer.wait()
+	// gin 请求中的慢查询使用请求的 trace id
+	gin.SetMode(gin.ReleaseMode)
+	app := gin.New()
+	app.Use(GinLoggerWithConfig(GinLoggerConfig{
+		TraceIDFunc: func(*gin.Context) string { return "trace-explain" },
+		OutputPaths: []string{filepath.Join(t.TempDir(), "access.log")},
+	}))
+	app.GET("/products", func(c *gin.Context) {
+		db.WithContext(c).Where("price > ?", 10).Find(&ret)
+	})
+	app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/products", nil))
+	logger.explainer.wait()
+
+	var explains []map[string]interface{}
+	for _, log := range readTestLogs(t, logfile) {
+		if log["trace_id"] == "trace-explain" && (log["msg"] == "sql explain" || log["msg"] == "sql explain failed") {
+			explains = append(explains, log)
+		}
+	}
+	if len(explains) != 2 {
+		t.Fatal("invalid explain logs count", len(explains), explains)
+	}
+	for _, explain := range explains {
+		if explain["msg"] != "sql explain" {
+			t.Error("invalid explain log", explain)
+		}
+	}
+	plan, _ := explains[0]["explain"].([]interface{})
+	if len(plan) == 0 || plan[0].(map[string]interface{})["detail"] == nil {
+		t.Error("invalid explain plan", explains[0]["explain"])
+	}
+}
+
+func TestGormExplainerAllow(t *testing.T) {
+	e := newGormExplainer(time.Minute, 0)
+	now := time.Now()
+	if !e.allow("a", now) || e.allow("a", now.Add(time.Second)) || !e.allow("b", now) {
+		t.Error("invalid allow")
+	}
+	if !e.allow("a", now.Add(time.Minute)) {
+		t.Error("should allow after interval")
+	}
+}
+
+func TestGormExplainerAcquire(t *testing.T) {
+	e := newGormExplainer(time.Minute, 0)
+	now := time.Now()
+	for i := 0; i < gormExplainConcurrency; i++ {
+		e.sem <- struct{}{}
+	}
+	if e.acquire("a", now) {
+		t.Error("should not acquire when concurrency is full")
+	}
+	<-e.sem
+	// 并发已满时跳过的查询结构不被限流
+	if !e.acquire("a", now.Add(time.Second)) {
+		t.Error("skipped fingerprint should not be suppressed")
+	}
+	<-e.sem
+	if e.acquire("a", now.Add(2*time.Second)) || len(e.sem) != gormExplainConcurrency-1 {
+		t.Error("suppressed fingerprint should release the semaphore", len(e.sem))
+	}
+}
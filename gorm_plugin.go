@@ -0,0 +1,201 @@
+package logit
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// GormPluginName 通过 db.Use(logger) 注册的 gorm 插件名称
+	GormPluginName = "logit:gorm"
+	// ctxGormStatementKey 在 context 中保存当前执行的 gorm statement
+	ctxGormStatementKey CtxKey = "_log_gorm_statement_"
+	// 查找应用代码 caller 时最多检查的栈帧数
+	gormCallerMaxDepth = 64
+)
+
+// logit 包路径，用于识别 gorm logger 自身的栈帧
+var logitPkgPath = reflect.TypeOf(GormLogger{}).PkgPath()
+
+// gormStatementInfo 当前执行的 gorm statement 和操作类型
+type gormStatementInfo struct {
+	stmt      *gorm.Statement
+	operation string
+}
+
+// Name
+//
+//	@Description: 实现 gorm.Plugin 接口方法
+//	@receiver g
+//	@return string
+func (g GormLogger) Name() string {
+	return GormPluginName
+}
+
+// Initialize
+//
+//	@Description: 实现 gorm.Plugin 接口方法，通过 db.Use(logger) 注册
+//	在 sql 执行前将 statement 和操作类型保存到 context 中， Trace 中可以获取 sql 模板、参数、表名、 model 和事务信息
+//	@receiver g
+//	@param db
+//	@return error
+func (g GormLogger) Initialize(db *gorm.DB) error {
+	callback := db.Callback()
+	for operation, register := range map[string]func(name string, fn func(*gorm.DB)) error{
+		"create": callback.Create().Before("*").Register,
+		"query":  callback.Query().Before("*").Register,
+		"update": callback.Update().Before("*").Register,
+		"delete": callback.Delete().Before("*").Register,
+		"row":    callback.Row().Before("*").Register,
+		"raw":    callback.Raw().Before("*").Register,
+	} {
+		if err := register(GormPluginName+":"+operation, gormWithStatement(operation)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// gormWithStatement
+//
+//	@Description: 生成将 statement 和操作类型保存到 context 中的 callback
+//	@param operation 操作类型， row 按 query 记录
+//	@return func(*gorm.DB)
+func gormWithStatement(operation string) func(*gorm.DB) {
+	if operation == "row" {
+		operation = "query"
+	}
+	return func(db *gorm.DB) {
+		ctx := gormUnwrapGinContext(db.Statement.Context)
+		// statement 复用时不重复添加
+		if info := gormStatement(ctx); info != nil && info.stmt == db.Statement && info.operation == operation {
+			return
+		}
+		db.Statement.Context = context.WithValue(ctx, ctxGormStatementKey, &gormStatementInfo{stmt: db.Statement, operation: operation})
+	}
+}
+
+// gormUnwrapGinContext
+//
+//	@Description: 将 gin.Context 转换为 c.Request 的 context ，并复制 gin.Context 中的 trace id 和 ctxLogger
+//	包装后的 gin.Context 不再是 *gin.Context ， gin 对非 string 类型的 key 返回 nil ，会丢失 trace id 、 sql 统计和累积字段
+//	@param ctx
+//	@return context.Context
+func gormUnwrapGinContext(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	gc, ok := ctx.(*gin.Context)
+	if !ok {
+		return ctx
+	}
+	unwrapped := context.Background()
+	if gc.Request != nil {
+		unwrapped = gc.Request.Context()
+	}
+	unwrapped = context.WithValue(unwrapped, TraceIDKeyName, CtxTraceID(gc))
+	if ctxLogger := storedCtxLogger(gc); ctxLogger != nil {
+		unwrapped = context.WithValue(unwrapped, CtxLoggerName, ctxLogger)
+	}
+	return unwrapped
+}
+
+// gormStatement
+//
+//	@Description: 从 context 中获取当前执行的 gorm statement ，未注册插件时返回 nil
+//	@param ctx
+//	@return *gormStatementInfo
+func gormStatement(ctx context.Context) *gormStatementInfo {
+	if ctx == nil {
+		return nil
+	}
+	info, _ := ctx.Value(ctxGormStatementKey).(*gormStatementInfo)
+	return info
+}
+
+// fields
+//
+//	@Description: 生成 table 、 operation 、 model 和 tx_id 字段
+//	@receiver info
+//	@return []zap.Field
+func (info *gormStatementInfo) fields() []zap.Field {
+	fields := []zap.Field{zap.String("operation", info.operation)}
+	if info.stmt.Table != "" {
+		fields = append(fields, zap.String("table", info.stmt.Table))
+	}
+	if model := gormModelName(info.stmt); model != "" {
+		fields = append(fields, zap.String("model", model))
+	}
+	// 同一事务中的 sql 使用同一个连接，以连接地址作为事务 ID
+	if _, ok := info.stmt.ConnPool.(gorm.TxCommitter); ok {
+		fields = append(fields, zap.String("tx_id", fmt.Sprintf("%p", info.stmt.ConnPool)))
+	}
+	return fields
+}
+
+// gormModelName
+//
+//	@Description: 获取 statement 对应的 model 类型名
+//	@param stmt
+//	@return string
+func gormModelName(stmt *gorm.Statement) string {
+	if stmt.Schema != nil && stmt.Schema.ModelType != nil {
+		return stmt.Schema.ModelType.String()
+	}
+	if stmt.Model == nil {
+		return ""
+	}
+	t := reflect.TypeOf(stmt.Model)
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return ""
+	}
+	return t.String()
+}
+
+// isGormFrame
+//
+//	@Description: 是否是 gorm 或 gorm logger 自身的栈帧
+//	@param function 栈帧的函数全名
+//	@return bool
+func isGormFrame(function string) bool {
+	if strings.HasPrefix(function, "gorm.io/") {
+		return true
+	}
+	if !strings.HasPrefix(function, logitPkgPath+".") {
+		return false
+	}
+	name := strings.TrimPrefix(function, logitPkgPath+".")
+	return strings.HasPrefix(name, "GormLogger.") || strings.HasPrefix(name, "(*GormLogger).") || strings.HasPrefix(name, "gorm")
+}
+
+// gormCallerSkip
+//
+//	@Description: 计算调用 GormLogger 方法的栈帧到第一个应用代码栈帧的层数
+//	@param skip 调用方到 GormLogger 方法的层数
+//	@return int
+//	@return bool 没有找到应用代码栈帧时返回 false
+func gormCallerSkip(skip int) (int, bool) {
+	pcs := make([]uintptr, gormCallerMaxDepth)
+	// 跳过 runtime.Callers 、 gormCallerSkip 和 GormLogger 方法之间的栈帧
+	n := runtime.Callers(skip+3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for i := 1; ; i++ {
+		frame, more := frames.Next()
+		if !isGormFrame(frame.Function) {
+			return i, true
+		}
+		if !more {
+			return 0, false
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package logit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestGormLoggerParameterizedSQL(t *testing.T) {
+	logfile := filepath.Join(t.TempDir(), "gorm.log")
+	logger, err := NewGormLogger(GormLoggerOptions{
+		OutputPaths:       []string{logfile},
+		ParameterizedSQL:  true,
+		EnableFingerprint: true,
+		VarsRedactor: func(ctx context.Context, sql string, vars []interface{}) []interface{} {
+			for i, v := range vars {
+				if s, ok := v.(string); ok && s == "secret" {
+					vars[i] = "***"
+				}
+			}
+			return vars
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "sqlite3.db")), &gorm.Config{Logger: logger})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(logger); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&Product{}); err != nil {
+		t.Fatal(err)
+	}
+	db.Create(&Product{Code: "secret", Price: 100})
+	var ret []Product
+	db.Where("code = ? AND price > ?", "secret", 10).Find(&ret)
+	db.Where("code = ? AND price > ?", "other", 20).Find(&ret)
+
+	var queries []map[string]interface{}
+	for _, log := range readTestLogs(t, logfile) {
+		if sql, _ := log["sql"].(string); strings.HasPrefix(sql, "SELECT * FROM `products` WHERE (code") {
+			queries = append(queries, log)
+		}
+	}
+	if len(queries) != 2 {
+		t.Fatal("invalid query logs count", len(queries))
+	}
+	if queries[0]["sql"] != "SELECT * FROM `products` WHERE (code = ? AND price > ?) AND `products`.`deleted_at` IS NULL" {
+		t.Error("sql should be parameterized", queries[0]["sql"])
+	}
+	if vars := queries[0]["vars"].([]interface{}); len(vars) != 2 || vars[0] != "***" || vars[1].(float64) != 10 {
+		t.Error("invalid vars", vars)
+	}
+	if queries[0]["fingerprint"] == "" || queries[0]["fingerprint"] != queries[1]["fingerprint"] {
+		t.Error("same query shape should have same fingerprint", queries[0]["fingerprint"], queries[1]["fingerprint"])
+	}
+}
+
+func TestGormLoggerPlugin(t *testing.T) {
+	logfile := filepath.Join(t.TempDir(), "gorm.log")
+	logger, err := NewGormLogger(GormLoggerOptions{OutputPaths: []string{logfile}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "sqlite3.db")), &gorm.Config{Logger: logger})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(logger); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&Product{}); err != nil {
+		t.Fatal(err)
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		tx.Create(&Product{Code: "a"})
+		return tx.Model(&Product{}).Where("code = ?", "a").Update("price", 10).Error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ret []Product
+	db.Find(&ret)
+	db.Exec("DELETE FROM products")
+
+	logs := readTestLogs(t, logfile)
+	byOperation := map[string][]map[string]interface{}{}
+	for _, log := range logs {
+		if op, ok := log["operation"].(string); ok {
+			byOperation[op] = append(byOperation[op], log)
+		}
+	}
+	for _, op := range []string{"create", "update", "query"} {
+		if len(byOperation[op]) == 0 {
+			t.Fatal("missing operation", op, logs)
+		}
+		log := byOperation[op][len(byOperation[op])-1]
+		if log["table"] != "products" || log["model"] != "logit.Product" {
+			t.Error("invalid table or model", log)
+		}
+		if caller, _ := log["caller"].(string); !strings.Contains(caller, "gorm_plugin_test.go") {
+			t.Error("caller should be application code", caller)
+		}
+	}
+	create, update := byOperation["create"][0], byOperation["update"][0]
+	if create["tx_id"] == nil || create["tx_id"] != update["tx_id"] {
+		t.Error("sql in same transaction should have same tx_id", create["tx_id"], update["tx_id"])
+	}
+	if byOperation["query"][0]["tx_id"] != nil {
+		t.Error("query outside transaction should not have tx_id")
+	}
+	if raw := byOperation["raw"]; raw[len(raw)-1]["sql"] != "DELETE FROM products" || raw[len(raw)-1]["model"] != nil {
+		t.Error("invalid raw log", raw[len(raw)-1])
+	}
+}
+
+func TestGormLoggerPluginGinContext(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	dir := t.TempDir()
+	gormLogfile, accessLogfile := filepath.Join(dir, "gorm.log"), filepath.Join(dir, "access.log")
+	logger, err := NewGormLogger(GormLoggerOptions{OutputPaths: []string{gormLogfile}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := gorm.Open(sqlite.Open(filepath.Join(dir, "sqlite3.db")), &gorm.Config{Logger: logger})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(logger); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&Product{}); err != nil {
+		t.Fatal(err)
+	}
+
+	app := gin.New()
+	app.Use(GinLoggerWithConfig(GinLoggerConfig{
+		TraceIDFunc: func(*gin.Context) string { return "trace-plugin" },
+		OutputPaths: []string{accessLogfile},
+	}))
+	app.GET("/products", func(c *gin.Context) {
+		var ret []Product
+		db.WithContext(c).Where("price > ?", 10).Find(&ret)
+		_ = db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+			return tx.Create(&Product{Code: "gin"}).Error
+		})
+		c.String(200, "ok")
+	})
+	app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/products", nil))
+
+	access := readTestLogs(t, accessLogfile)
+	if len(access) != 1 || access[0]["trace_id"] != "trace-plugin" {
+		t.Fatal("invalid access log", access)
+	}
+	var query, create map[string]interface{}
+	for _, log := range readTestLogs(t, gormLogfile) {
+		sql, _ := log["sql"].(string)
+		switch {
+		case strings.HasPrefix(sql, "SELECT * FROM `products` WHERE price"):
+			query = log
+		case strings.HasPrefix(sql, "INSERT INTO `products`"):
+			create = log
+		}
+	}
+	// 注册插件后 gin.Context 中的 trace id 仍然传递到 sql 日志，同时记录插件提供的字段
+	for _, log := range []map[string]interface{}{query, create} {
+		if log == nil || log["trace_id"] != access[0]["trace_id"] {
+			t.Fatal("sql log should have the request trace id", log)
+		}
+		if log["table"] != "products" || log["model"] != "logit.Product" {
+			t.Error("invalid table or model", log)
+		}
+		if caller, _ := log["caller"].(string); !strings.Contains(caller, "gorm_plugin_test.go") {
+			t.Error("caller should be application code", caller)
+		}
+	}
+	if query["operation"] != "query" || query["tx_id"] != nil {
+		t.Error("invalid query log", query)
+	}
+	if create["operation"] != "create" || create["tx_id"] == nil {
+		t.Error("invalid create log", create)
+	}
+}
@@ -0,0 +1,108 @@
+package logit
+
+import (
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	// 占位符列表，如 IN (?, ?, ?)
+	gormSQLPlaceholderListRe = regexp.MustCompile(`\(\s*\?(\s*,\s*\?)*\s*\)`)
+	// 批量插入的多组值，如 VALUES (?+), (?+)
+	gormSQLPlaceholderRowsRe = regexp.MustCompile(`\(\?\+\)(\s*,\s*\(\?\+\))+`)
+)
+
+// NormalizeSQL
+//
+//	@Description: 将 sql 归一化为查询结构，相同结构不同参数的 sql 归一化结果相同
+//	字符串、数字和 $1 形式的占位符替换为 ? ，占位符列表替换为 (?+) ，合并空白字符，关键字转为小写，引号中的标识符保持不变
+//	@param sql 带占位符的 sql 模板或已填充参数的 sql
+//	@return string
+func NormalizeSQL(sql string) string {
+	var buf strings.Builder
+	buf.Grow(len(sql))
+	runes := []rune(sql)
+	space := false
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			space = buf.Len() > 0
+			continue
+		case r == '\'':
+			// 字符串，支持 '' 和 \' 转义
+			for i++; i < len(runes); i++ {
+				if runes[i] == '\\' {
+					i++
+				} else if runes[i] == '\'' {
+					if i+1 < len(runes) && runes[i+1] == '\'' {
+						i++
+						continue
+					}
+					break
+				}
+			}
+			r = '?'
+		case r == '"' || r == '`':
+			// 引号中的标识符
+			end := i + 1
+			for end < len(runes) && runes[end] != r {
+				end++
+			}
+			if end >= len(runes) {
+				end = len(runes) - 1
+			}
+			if space {
+				buf.WriteByte(' ')
+				space = false
+			}
+			buf.WriteString(string(runes[i : end+1]))
+			i = end
+			continue
+		case r == '$' && i+1 < len(runes) && unicode.IsDigit(runes[i+1]):
+			// postgres 占位符 $1
+			for i+1 < len(runes) && unicode.IsDigit(runes[i+1]) {
+				i++
+			}
+			r = '?'
+		case unicode.IsDigit(r) && (i == 0 || !isSQLIdentRune(runes[i-1])):
+			// 数字，如 1 、 1.5 、 1e10 、 0x1f
+			for i+1 < len(runes) && (isSQLIdentRune(runes[i+1]) || runes[i+1] == '.') {
+				i++
+			}
+			r = '?'
+		default:
+			r = unicode.ToLower(r)
+		}
+		if space {
+			buf.WriteByte(' ')
+			space = false
+		}
+		buf.WriteRune(r)
+	}
+	normalized := gormSQLPlaceholderListRe.ReplaceAllString(buf.String(), "(?+)")
+	return gormSQLPlaceholderRowsRe.ReplaceAllString(normalized, "(?+)")
+}
+
+// isSQLIdentRune
+//
+//	@Description: 是否是 sql 标识符中的字符
+//	@param r
+//	@return bool
+func isSQLIdentRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// SQLFingerprint
+//
+//	@Description: 计算 sql 查询结构的指纹，即 NormalizeSQL 结果的 fnv-1a 64 位 hash ，用于按查询结构聚合日志
+//	@param sql
+//	@return string 16 位十六进制字符串
+func SQLFingerprint(sql string) string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(NormalizeSQL(sql)))
+	return fmt.Sprintf("%016x", h.Sum64())
+}
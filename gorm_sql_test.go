@@ -0,0 +1,30 @@
+package logit
+
+import "testing"
+
+func TestNormalizeSQL(t *testing.T) {
+	cases := map[string]string{
+		"SELECT * FROM `users` WHERE id = 1 AND name = 'it''s'":          "select * from `users` where id = ? and name = ?",
+		"SELECT * FROM `users` WHERE id = ? AND name = ?":                "select * from `users` where id = ? and name = ?",
+		`SELECT * FROM "users" WHERE id = $1 AND  score > 1.5`:           `select * from "users" where id = ? and score > ?`,
+		"SELECT * FROM t1 WHERE id IN (1, 2, 3) AND c = 'a\\'b'":         "select * from t1 where id in (?+) and c = ?",
+		"INSERT INTO `t` (`a`,`b`) VALUES (?,?),(?,?),(?,?)":             "insert into `t` (`a`,`b`) values (?+)",
+		"SELECT * FROM `Products` WHERE `Products`.`deleted_at` IS NULL": "select * from `Products` where `Products`.`deleted_at` is null",
+	}
+	for sql, want := range cases {
+		if got := NormalizeSQL(sql); got != want {
+			t.Errorf("NormalizeSQL(%q)\n got: %s\nwant: %s", sql, got, want)
+		}
+	}
+}
+
+func TestSQLFingerprint(t *testing.T) {
+	a := SQLFingerprint("SELECT * FROM users WHERE id = 1 AND id IN (1,2)")
+	b := SQLFingerprint("select *\n from users where id = ? and id in (?)")
+	if a != b || len(a) != 16 {
+		t.Error("invalid fingerprint", a, b)
+	}
+	if a == SQLFingerprint("SELECT * FROM orders WHERE id = 1") {
+		t.Error("different sql should have different fingerprint")
+	}
+}
@@ -0,0 +1,125 @@
+package logit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	// context 中保存请求级别 sql 统计的 key
+	ctxQueryStatsKey CtxKey = "_log_query_stats_"
+)
+
+// QueryStats 单个请求中 GormLogger 记录的 sql 执行次数和耗时，可以在多个 goroutine 中并发记录
+type QueryStats struct {
+	mu           sync.Mutex
+	queries      int64
+	duration     time.Duration
+	fingerprints map[string]int
+}
+
+// record
+//
+//	@Description: 记录一次 sql 执行
+//	@receiver s
+//	@param fingerprint sql 查询结构的指纹，为空时不统计重复次数
+//	@param d sql 执行耗时
+//	@return int64 请求中 sql 的执行次数
+//	@return int 相同结构 sql 的执行次数
+func (s *QueryStats) record(fingerprint string, d time.Duration) (int64, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queries++
+	s.duration += d
+	if fingerprint == "" {
+		return s.queries, 0
+	}
+	if s.fingerprints == nil {
+		s.fingerprints = map[string]int{}
+	}
+	s.fingerprints[fingerprint]++
+	return s.queries, s.fingerprints[fingerprint]
+}
+
+// Queries
+//
+//	@Description: 获取 sql 执行次数
+//	@receiver s
+//	@return int64
+func (s *QueryStats) Queries() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.queries
+}
+
+// Duration
+//
+//	@Description: 获取 sql 执行总耗时
+//	@receiver s
+//	@return time.Duration
+func (s *QueryStats) Duration() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.duration
+}
+
+// fields
+//
+//	@Description: 生成访问日志中的 db_queries 和 db_time （秒）字段，没有执行 sql 时不记录
+//	@receiver s
+//	@return []zap.Field
+func (s *QueryStats) fields() []zap.Field {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.queries == 0 {
+		return nil
+	}
+	return []zap.Field{
+		zap.Int64("db_queries", s.queries),
+		zap.Float64("db_time", s.duration.Seconds()),
+	}
+}
+
+// CtxQueryStats
+//
+//	@Description: 获取 context 中的 sql 统计， gin.Context 从 c.Request 的 context 中获取，不存在时返回 nil
+//	@param c
+//	@return *QueryStats
+func CtxQueryStats(c context.Context) *QueryStats {
+	if c == nil {
+		return nil
+	}
+	if gc, ok := c.(*gin.Context); ok {
+		if gc.Request == nil {
+			return nil
+		}
+		c = gc.Request.Context()
+	}
+	stats, _ := c.Value(ctxQueryStatsKey).(*QueryStats)
+	return stats
+}
+
+// WithQueryStats
+//
+//	@Description: 在 context 中创建新的 sql 统计，使用返回的 context 执行 sql 时由 GormLogger 记录
+//	GinLogger 会为每个请求创建， gin.Context 会替换 c.Request
+//	@param c
+//	@return context.Context
+//	@return *QueryStats
+func WithQueryStats(c context.Context) (context.Context, *QueryStats) {
+	if c == nil {
+		c = context.Background()
+	}
+	stats := &QueryStats{}
+	if gc, ok := c.(*gin.Context); ok {
+		if gc.Request != nil {
+			gc.Request = gc.Request.WithContext(context.WithValue(gc.Request.Context(), ctxQueryStatsKey, stats))
+		}
+		return gc, stats
+	}
+	return context.WithValue(c, ctxQueryStatsKey, stats), stats
+}
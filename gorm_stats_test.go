@@ -0,0 +1,100 @@
+package logit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestGormQueryStats(t *testing.T) {
+	t.Run("logger", func(t *testing.T) { testGormQueryStats(t, false) })
+	// 注册插件后 statement context 被包装，统计仍然记录到请求中
+	t.Run("plugin", func(t *testing.T) { testGormQueryStats(t, true) })
+}
+
+func testGormQueryStats(t *testing.T, usePlugin bool) {
+	gin.SetMode(gin.ReleaseMode)
+	dir := t.TempDir()
+	gormLogfile, accessLogfile := filepath.Join(dir, "gorm.log"), filepath.Join(dir, "access.log")
+	logger, err := NewGormLogger(GormLoggerOptions{
+		OutputPaths:            []string{gormLogfile},
+		RepeatedQueryThreshold: 2,
+		QueryBudget:            3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := gorm.Open(sqlite.Open(filepath.Join(dir, "sqlite3.db")), &gorm.Config{Logger: logger})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usePlugin {
+		if err := db.Use(logger); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.AutoMigrate(&Product{}); err != nil {
+		t.Fatal(err)
+	}
+
+	app := gin.New()
+	app.Use(GinLoggerWithConfig(GinLoggerConfig{
+		TraceIDFunc: func(*gin.Context) string { return "trace-stats" },
+		OutputPaths: []string{accessLogfile},
+	}))
+	app.GET("/products", func(c *gin.Context) {
+		var ret Product
+		for i := 0; i < 4; i++ {
+			db.WithContext(c).Where("id = ?", i).Limit(1).Find(&ret)
+		}
+		c.String(200, "ok")
+	})
+	app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/products", nil))
+
+	access := readTestLogs(t, accessLogfile)
+	if len(access) != 1 || access[0]["db_queries"].(float64) != 4 || access[0]["db_time"].(float64) <= 0 {
+		t.Fatal("invalid access log", access)
+	}
+	var repeated, budget int
+	for _, log := range readTestLogs(t, gormLogfile) {
+		switch log["msg"] {
+		case "sql repeated in request":
+			repeated++
+			if log["trace_id"] != "trace-stats" || log["repeated"].(float64) != 3 {
+				t.Error("invalid repeated log", log)
+			}
+		case "sql queries exceed budget":
+			budget++
+			if log["queries"].(float64) != 4 {
+				t.Error("invalid budget log", log)
+			}
+		}
+	}
+	if repeated != 1 || budget != 1 {
+		t.Error("warn logs should be written once per request", repeated, budget)
+	}
+}
+
+func TestWithQueryStats(t *testing.T) {
+	if CtxQueryStats(context.Background()) != nil {
+		t.Error("stats should be nil")
+	}
+	ctx, stats := WithQueryStats(context.Background())
+	if CtxQueryStats(ctx) != stats {
+		t.Error("stats not found in context")
+	}
+	stats.record("a", time.Second)
+	if _, n := stats.record("a", time.Second); n != 2 {
+		t.Error("invalid repeated count", n)
+	}
+	if stats.Queries() != 2 || stats.Duration() != 2*time.Second {
+		t.Error("invalid stats", stats.Queries(), stats.Duration())
+	}
+}
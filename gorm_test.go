@@ -1,13 +1,17 @@
 package logit
 
 import (
+	"context"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"go.uber.org/zap/zapcore"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
 )
 
 // Product test model
@@ -55,3 +59,58 @@ func TestGormLogger(t *testing.T) {
 		t.Log(err)
 	}
 }
+
+func TestGormLoggerWithConfig(t *testing.T) {
+	dir := t.TempDir()
+	logfile := filepath.Join(dir, "gorm.log")
+	logger, err := NewGormLogger(GormLoggerOptions{
+		OutputPaths: []string{logfile},
+		GormConfig: &gormlogger.Config{
+			LogLevel:                  gormlogger.Warn,
+			IgnoreRecordNotFoundError: true,
+			ParameterizedQueries:      true,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := gorm.Open(sqlite.Open(filepath.Join(dir, "sqlite3.db")), &gorm.Config{Logger: logger})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&Product{}); err != nil {
+		t.Fatal(err)
+	}
+	var ret Product
+	// Warn 级别不记录普通 sql ，忽略 record not found
+	db.Where("code = ?", "missing").First(&ret)
+	db.Where("code = ?", "error").Table("missing_table").Find(&ret)
+	// Silent 不记录错误
+	db.Session(&gorm.Session{Logger: logger.LogMode(gormlogger.Silent)}).Table("silent_table").Find(&ret)
+
+	logs := readTestLogs(t, logfile)
+	if len(logs) != 1 {
+		t.Fatal("invalid logs count", len(logs), logs)
+	}
+	if logs[0]["level"] != "ERROR" || logs[0]["sql"] != "SELECT * FROM `missing_table` WHERE code = ? AND `missing_table`.`deleted_at` IS NULL" {
+		t.Error("invalid error log", logs[0])
+	}
+
+	if _, err := NewGormLoggerWithConfig(gormlogger.Config{Colorful: true, LogLevel: gormlogger.Info}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGormLoggerParamsFilter(t *testing.T) {
+	logger, _ := NewGormLogger(GormLoggerOptions{OutputPaths: []string{filepath.Join(t.TempDir(), "gorm.log")}})
+	if _, vars := logger.ParamsFilter(context.Background(), "SELECT ?", 1); len(vars) != 1 {
+		t.Error("params should be kept", vars)
+	}
+	logger, _ = NewGormLogger(GormLoggerOptions{
+		OutputPaths: []string{filepath.Join(t.TempDir(), "gorm.log")},
+		GormConfig:  &gormlogger.Config{ParameterizedQueries: true},
+	})
+	if sql, vars := logger.ParamsFilter(context.Background(), "SELECT ?", 1); len(vars) != 0 || !strings.Contains(sql, "?") {
+		t.Error("params should be filtered", sql, vars)
+	}
+}
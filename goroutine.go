@@ -0,0 +1,104 @@
+package logit
+
+import (
+	"context"
+	"runtime"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// 后台 goroutine 日志的 logger 名称
+const goroutineLoggerName = "goroutine"
+
+// Detach
+//
+//	@Description: 将 context 中的 trace id 和 ctxLogger 复制到新的 context.Context ，新 context 不会被取消，也没有 deadline
+//	用于在 gin handler 中启动 goroutine ，请求结束后 *gin.Context 会被复用，不能在 goroutine 中继续使用
+//	AddAccessFields 累积的字段会添加到复制的 ctxLogger 中
+//	@param c 支持 *gin.Context 和 context.Context
+//	@return context.Context
+func Detach(c context.Context) context.Context {
+	if c == nil {
+		return context.Background()
+	}
+	traceID := CtxTraceID(c)
+	ctxLogger := storedCtxLogger(c)
+	if ctxLogger == nil {
+		ctxLogger = CloneLogger(string(CtxLoggerName)).With(zap.String(string(TraceIDKeyName), traceID))
+	}
+	if accumulated := AccessFields(c); len(accumulated) > 0 {
+		ctxLogger = ctxLogger.With(accumulated...)
+	}
+	detached := context.WithValue(context.Background(), TraceIDKeyName, traceID)
+	return context.WithValue(detached, CtxLoggerName, ctxLogger)
+}
+
+// Go
+//
+//	@Description: 在新的 goroutine 中执行 fn ，打印开始、结束和 panic 日志，日志带有 ctx 中的 trace id ， caller 为调用 Go 的位置
+//	fn 中的 panic 会被 recover 并按 Error 级别打印，不会导致程序退出
+//	c 为 *gin.Context 时会先调用 Detach ，其他 context 保持原样传给 fn ，不想随请求取消时可以先调用 Detach
+//	@param c
+//	@param fn
+//	@param fields 添加到开始、结束和 panic 日志中的字段，可以用来标识任务
+func Go(c context.Context, fn func(ctx context.Context), fields ...zap.Field) {
+	if c == nil {
+		c = context.Background()
+	}
+	if _, ok := c.(*gin.Context); ok {
+		c = Detach(c)
+	}
+	var caller zapcore.EntryCaller
+	if pc, _, _, ok := runtime.Caller(1); ok {
+		frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+		caller = zapcore.EntryCaller{Defined: true, PC: frame.PC, File: frame.File, Line: frame.Line, Function: frame.Function}
+	}
+	// 关闭 logger 自动添加的 stacktrace ，只在 panic 日志中手动记录
+	logger := CtxLogger(c, fields...).Named(goroutineLoggerName).WithOptions(zap.AddStacktrace(zapcore.FatalLevel))
+	logGoroutine(logger, caller, zap.InfoLevel, "goroutine start")
+	go runGoroutine(c, logger, caller, fn)
+}
+
+// runGoroutine
+//
+//	@Description: 执行 fn 并打印结束或 panic 日志
+//	@param c
+//	@param logger
+//	@param caller 调用 Go 的位置
+//	@param fn
+func runGoroutine(c context.Context, logger *zap.Logger, caller zapcore.EntryCaller, fn func(ctx context.Context)) {
+	begin := time.Now()
+	defer func() {
+		if err := recover(); err != nil {
+			logGoroutine(logger, caller, zap.ErrorLevel, "goroutine panic",
+				zap.Any("panic", err),
+				zap.Float64("latency", time.Since(begin).Seconds()),
+				zap.Stack("stacktrace"),
+			)
+		}
+	}()
+	fn(c)
+	logGoroutine(logger, caller, zap.InfoLevel, "goroutine finish", zap.Float64("latency", time.Since(begin).Seconds()))
+}
+
+// logGoroutine
+//
+//	@Description: 打印日志，开启 caller 时使用调用 Go 的位置
+//	@param logger
+//	@param caller
+//	@param level
+//	@param msg
+//	@param fields
+func logGoroutine(logger *zap.Logger, caller zapcore.EntryCaller, level zapcore.Level, msg string, fields ...zap.Field) {
+	ce := logger.Check(level, msg)
+	if ce == nil {
+		return
+	}
+	if ce.Entry.Caller.Defined && caller.Defined {
+		ce.Entry.Caller = caller
+	}
+	ce.Write(fields...)
+}
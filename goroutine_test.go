@@ -0,0 +1,83 @@
+package logit
+
+import (
+	"context"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func TestDetach(t *testing.T) {
+	logfile := filepath.Join(t.TempDir(), "detach.log")
+	logger, _ := NewLogger(Options{Format: "json", OutputPaths: []string{logfile}})
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/", nil)
+	NewCtxLogger(c, logger, "trace-detach")
+	AddAccessFields(c, zap.String("user", "a"))
+
+	ctx := Detach(c)
+	if _, ok := ctx.(*gin.Context); ok || ctx.Done() != nil {
+		t.Fatal("detached context should not be cancelable")
+	}
+	if CtxTraceID(ctx) != "trace-detach" {
+		t.Error("trace id should be copied", CtxTraceID(ctx))
+	}
+	CtxLogger(ctx).Info("detached")
+	logs := readTestLogs(t, logfile)
+	if len(logs) != 1 || logs[0]["trace_id"] != "trace-detach" || logs[0]["user"] != "a" {
+		t.Error("ctx logger should be copied", logs)
+	}
+
+	// 没有 ctxLogger 时 trace id 保持一致
+	ctx = Detach(context.WithValue(context.Background(), TraceIDKeyName, "trace-bg"))
+	if CtxTraceID(ctx) != "trace-bg" || Detach(nil) == nil {
+		t.Error("invalid detached context")
+	}
+}
+
+func TestGo(t *testing.T) {
+	logfile := filepath.Join(t.TempDir(), "go.log")
+	logger, _ := NewLogger(Options{Format: "json", OutputPaths: []string{logfile}})
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/", nil)
+	NewCtxLogger(c, logger, "trace-go")
+
+	done := make(chan string, 1)
+	Go(c, func(ctx context.Context) {
+		done <- CtxTraceID(ctx)
+	}, zap.String("task", "ok"))
+	if traceID := <-done; traceID != "trace-go" {
+		t.Error("fn should receive detached context", traceID)
+	}
+	Go(c, func(ctx context.Context) {
+		panic("boom")
+	}, zap.String("task", "panic"))
+
+	var logs []map[string]interface{}
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if logs = readTestLogs(t, logfile); len(logs) == 4 {
+			break
+		}
+	}
+	msgs := map[string]map[string]interface{}{}
+	for _, log := range logs {
+		msgs[log["task"].(string)+" "+log["msg"].(string)] = log
+		if log["trace_id"] != "trace-go" {
+			t.Error("invalid trace id", log)
+		}
+		if caller, _ := log["caller"].(string); !strings.Contains(caller, "goroutine_test.go") {
+			t.Error("caller should be the Go call site", caller)
+		}
+	}
+	if len(msgs) != 4 || msgs["ok goroutine start"] == nil || msgs["ok goroutine finish"]["latency"] == nil {
+		t.Fatal("invalid logs", logs)
+	}
+	if panicLog := msgs["panic goroutine panic"]; panicLog["level"] != "ERROR" || panicLog["panic"] != "boom" || panicLog["stacktrace"] == nil {
+		t.Error("invalid panic log", panicLog)
+	}
+}
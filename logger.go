@@ -38,6 +38,7 @@ type Options struct {
 	EncoderConfig     *zapcore.EncoderConfig // 配置日志字段 key 的名称
 	Sampling          *zap.SamplingConfig    // 配置日志字段 key 的名称
 	DisableSampling   bool                   // 禁用采样
+	LevelCounter      *LevelCounter          // 按 logger name 和级别统计日志条数，统计的是采样前的条数
 }
 
 const (
@@ -160,6 +161,11 @@ func NewLogger(options Options) (*zap.Logger, error) {
 		return nil, err
 	}
 
+	// 统计与 logger 级别一致的日志条数
+	if options.LevelCounter != nil {
+		logger = AttachCore(logger, &levelCounterCore{LevelEnabler: cfg.Level, counter: options.LevelCounter})
+	}
+
 	// 设置 baseLogger 名字，没有传参使用默认名字
 	if options.Name != "" {
 		logger = logger.Named(options.Name)
@@ -0,0 +1,121 @@
+package logit
+
+import (
+	"github.com/go-logr/logr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// LogrSink 实现 logr.LogSink ，通过 zap logger 打印 logr 的日志
+// logr 的 V(0) 使用 Info 级别， V(1) 及以上使用 Debug 级别
+type LogrSink struct {
+	logger *zap.Logger
+}
+
+// NewLogrSink
+//
+//	@Description: 创建 logr.LogSink
+//	@param logger 为 nil 时使用 CloneLogger("logr")
+//	@return *LogrSink
+func NewLogrSink(logger *zap.Logger) *LogrSink {
+	if logger == nil {
+		logger = CloneLogger("logr")
+	}
+	return &LogrSink{logger: logger}
+}
+
+// NewLogr
+//
+//	@Description: 创建使用 LogrSink 的 logr.Logger ，可用于 controller-runtime 、 klog 等使用 logr 的库
+//	@param logger 为 nil 时使用 CloneLogger("logr")
+//	@return logr.Logger
+func NewLogr(logger *zap.Logger) logr.Logger {
+	return logr.New(NewLogrSink(logger))
+}
+
+// Init
+//
+//	@Description: 实现 logr.LogSink 接口方法，跳过 logr.Logger 和 LogrSink 的栈帧
+//	@receiver s
+//	@param info
+func (s *LogrSink) Init(info logr.RuntimeInfo) {
+	s.logger = s.logger.WithOptions(zap.AddCallerSkip(info.CallDepth + 1))
+}
+
+// Enabled
+//
+//	@Description: 实现 logr.LogSink 接口方法
+//	@receiver s
+//	@param level
+//	@return bool
+func (s *LogrSink) Enabled(level int) bool {
+	return s.logger.Core().Enabled(logrZapLevel(level))
+}
+
+// Info
+//
+//	@Description: 实现 logr.LogSink 接口方法
+//	@receiver s
+//	@param level
+//	@param msg
+//	@param keysAndValues
+func (s *LogrSink) Info(level int, msg string, keysAndValues ...interface{}) {
+	if logrZapLevel(level) == zap.DebugLevel {
+		s.logger.Sugar().Debugw(msg, keysAndValues...)
+		return
+	}
+	s.logger.Sugar().Infow(msg, keysAndValues...)
+}
+
+// Error
+//
+//	@Description: 实现 logr.LogSink 接口方法
+//	@receiver s
+//	@param err
+//	@param msg
+//	@param keysAndValues
+func (s *LogrSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	s.logger.With(zap.Error(err)).Sugar().Errorw(msg, keysAndValues...)
+}
+
+// WithValues
+//
+//	@Description: 实现 logr.LogSink 接口方法
+//	@receiver s
+//	@param keysAndValues
+//	@return logr.LogSink
+func (s *LogrSink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	return &LogrSink{logger: s.logger.Sugar().With(keysAndValues...).Desugar()}
+}
+
+// WithName
+//
+//	@Description: 实现 logr.LogSink 接口方法
+//	@receiver s
+//	@param name
+//	@return logr.LogSink
+func (s *LogrSink) WithName(name string) logr.LogSink {
+	return &LogrSink{logger: s.logger.Named(name)}
+}
+
+// WithCallDepth
+//
+//	@Description: 实现 logr.CallDepthLogSink 接口方法，用于 logr.Logger.WithCallDepth 和 logr 的 helper 函数
+//	@receiver s
+//	@param depth
+//	@return logr.LogSink
+func (s *LogrSink) WithCallDepth(depth int) logr.LogSink {
+	return &LogrSink{logger: s.logger.WithOptions(zap.AddCallerSkip(depth))}
+}
+
+// logrZapLevel
+//
+//	@Description: logr 的 V 级别转换为 zap 日志级别
+//	@param level
+//	@return zapcore.Level
+func logrZapLevel(level int) zapcore.Level {
+	if level > 0 {
+		return zap.DebugLevel
+	}
+	return zap.InfoLevel
+}
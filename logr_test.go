@@ -0,0 +1,37 @@
+package logit
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLogr(t *testing.T) {
+	logfile := filepath.Join(t.TempDir(), "logr.log")
+	logger, _ := NewLogger(Options{Level: "info", Format: "json", OutputPaths: []string{logfile}, DisableStacktrace: true})
+	l := NewLogr(logger).WithName("controller").WithValues("id", 1)
+
+	l.Info("reconcile", "key", "ns/a")
+	l.V(1).Info("debug should be disabled")
+	l.Error(errors.New("boom"), "failed", "retry", true)
+	if l.V(1).Enabled() || !l.Enabled() {
+		t.Error("invalid enabled")
+	}
+
+	logs := readTestLogs(t, logfile)
+	if len(logs) != 2 {
+		t.Fatal("invalid logs", logs)
+	}
+	if logs[0]["level"] != "INFO" || logs[0]["logger"] != "logit.controller" || logs[0]["id"] != float64(1) || logs[0]["key"] != "ns/a" {
+		t.Error("invalid info log", logs[0])
+	}
+	if logs[1]["level"] != "ERROR" || logs[1]["error"] != "boom" || logs[1]["retry"] != true {
+		t.Error("invalid error log", logs[1])
+	}
+	for _, l := range logs {
+		if caller, _ := l["caller"].(string); !strings.Contains(caller, "logr_test.go") {
+			t.Error("caller should be application code", caller)
+		}
+	}
+}
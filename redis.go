@@ -12,7 +12,6 @@ import (
 	"errors"
 	"time"
 
-	"github.com/gin-gonic/gin"
 	"github.com/go-redis/redis/v8"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -23,7 +22,7 @@ const (
 	defaultRedisLoggerName = "redis"
 	// 默认 caller skip
 	defaultRedisLoggerCallerSkip = 4
-	// 上下文中保存开始时间的 key
+	// 上下文中保存命令开始时间的 key
 	ctxRedisStartKey CtxKey = "_log_redis_start_"
 	// 默认的 redis 慢查询时间，30ms
 	defaultSlowThreshold = time.Millisecond * 30
@@ -52,6 +51,25 @@ type RedisLoggerOptions struct {
 	EncoderConfig *zapcore.EncoderConfig
 	// nil err level
 	NilErrLevel string
+	// 需要隐藏参数值和返回值的 key 模式，使用 path.Match 匹配命令的第一个 key ，例如 "session:*"
+	// AUTH 、 HELLO 、 MIGRATE 的认证信息始终隐藏
+	// Optional.
+	RedactKeyPatterns []string
+	// 单个参数最大记录长度，超出部分截断，默认 0 不限制
+	// Optional.
+	MaxArgLength int
+	// 返回值最大记录长度，超出部分截断，默认 0 不限制
+	// Optional.
+	MaxReplyLength int
+	// 只记录命令名称和第一个 key ，不记录其他参数和返回值
+	// Optional.
+	OnlyCommandAndKey bool
+	// pipeline 中每个失败的命令额外打印一条 redis pipeline cmd 日志，默认 false
+	// Optional.
+	PipelineErrorDetails bool
+	// 按命令和 key 前缀统计次数、错误率、耗时分位数和热点 key ，并定期打印 redis stats 日志
+	// Optional.
+	Stats *RedisStats
 }
 
 type RedisLogger struct {
@@ -61,14 +79,23 @@ type RedisLogger struct {
 	callerSkip    int
 	_logger       *zap.Logger
 	nilErrLevel   string
+	formatter     *RedisCmdFormatter
+	// pipeline 中每个失败的命令是否单独打印日志
+	pipelineErrorDetails bool
+	stats                *RedisStats
+	// 表示 key 不存在的错误， go-redis v8 和 v9 的 redis.Nil 不同
+	nilErr error
 }
 
 func NewRedisLogger(opt RedisLoggerOptions) (RedisLogger, error) {
 	l := RedisLogger{
-		name:          defaultRedisLoggerName,
-		callerSkip:    defaultRedisLoggerCallerSkip,
-		slowThreshold: defaultSlowThreshold,
-		nilErrLevel:   opt.NilErrLevel,
+		name:                 defaultRedisLoggerName,
+		callerSkip:           defaultRedisLoggerCallerSkip,
+		slowThreshold:        defaultSlowThreshold,
+		nilErrLevel:          opt.NilErrLevel,
+		pipelineErrorDetails: opt.PipelineErrorDetails,
+		stats:                opt.Stats,
+		nilErr:               redis.Nil,
 	}
 	if opt.CallerSkip != 0 {
 		l.callerSkip = opt.CallerSkip
@@ -80,6 +107,9 @@ func NewRedisLogger(opt RedisLoggerOptions) (RedisLogger, error) {
 		l.slowThreshold = opt.SlowThreshold
 	}
 	var err error
+	if l.formatter, err = NewRedisCmdFormatter(opt); err != nil {
+		return l, err
+	}
 	l._logger, err = NewLogger(Options{
 		Level:             "debug",
 		Format:            "json",
@@ -89,8 +119,25 @@ func NewRedisLogger(opt RedisLoggerOptions) (RedisLogger, error) {
 		DisableStacktrace: opt.DisableStacktrace,
 		EncoderConfig:     opt.EncoderConfig,
 	})
+	if err != nil {
+		return l, err
+	}
 	l._logger = l._logger.Named(l.name)
-	return l, err
+	if l.stats != nil {
+		l.stats.Start(l._logger)
+	}
+	return l, nil
+}
+
+// WithNilErr
+//
+//	@Description: 返回使用 nilErr 作为 redis.Nil 的 RedisLogger ，用于 go-redis v9 等 redis.Nil 不同的版本复用日志逻辑
+//	@receiver l
+//	@param nilErr
+//	@return RedisLogger
+func (l RedisLogger) WithNilErr(nilErr error) RedisLogger {
+	l.nilErr = nilErr
+	return l
 }
 
 // CtxLogger
@@ -113,13 +160,7 @@ func (l RedisLogger) CtxLogger(ctx context.Context) *zap.Logger {
 //	@return context.Context
 //	@return error
 func (l RedisLogger) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
-	if gc, ok := ctx.(*gin.Context); ok {
-		// set start time in gin.Context
-		gc.Set(string(ctxRedisStartKey), time.Now())
-		return ctx, nil
-	}
-	// set start time in context
-	return context.WithValue(ctx, ctxRedisStartKey, time.Now()), nil
+	return withRedisStart(ctx), nil
 }
 
 // AfterProcess
@@ -130,43 +171,21 @@ func (l RedisLogger) BeforeProcess(ctx context.Context, cmd redis.Cmder) (contex
 //	@param cmd
 //	@return error
 func (l RedisLogger) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
-	logger := l.CtxLogger(ctx)
-	cost := l.getCost(ctx)
-	if err := cmd.Err(); err != nil {
-		level := zap.ErrorLevel
-		if errors.Is(err, redis.Nil) {
-			var err1 error
-			if level, err1 = zapcore.ParseLevel(l.nilErrLevel); err1 != nil {
-				level = zap.ErrorLevel
-			}
-		}
-		logger.Log(level, "redis trace", zap.String("command", cmd.FullName()), zap.String("args", cmd.String()), zap.Float64("latency_ms", cost), zap.Error(err))
-	} else {
-		log := logger.Info
-		if cost > float64(l.slowThreshold) {
-			log = logger.Warn
-		}
-		log("redis trace", zap.String("command", cmd.FullName()), zap.String("args", cmd.String()), zap.Float64("latency_ms", cost))
-	}
+	ctx, elapsed := redisElapsed(ctx)
+	l.LogCmd(l.CtxLogger(ctx), cmd, cmd.Err(), elapsed)
 	return nil
 }
 
 // BeforeProcessPipeline
 //
-//	@Description:
+//	@Description: 实现 go-redis HOOK BeforeProcessPipeline 方法
 //	@receiver l
 //	@param ctx
 //	@param cmds
 //	@return context.Context
 //	@return error
 func (l RedisLogger) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
-	if gc, ok := ctx.(*gin.Context); ok {
-		// set start time in gin.Context
-		gc.Set(string(ctxRedisStartKey), time.Now())
-		return ctx, nil
-	}
-	// set start time in context
-	return context.WithValue(ctx, ctxRedisStartKey, time.Now()), nil
+	return withRedisStart(ctx), nil
 }
 
 // AfterProcessPipeline
@@ -177,40 +196,190 @@ func (l RedisLogger) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmd
 //	@param cmds
 //	@return error
 func (l RedisLogger) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
-	logger := l.CtxLogger(ctx)
-	cost := l.getCost(ctx)
+	ctx, elapsed := redisElapsed(ctx)
+	pipeline := make([]RedisCmd, len(cmds))
+	for i, cmd := range cmds {
+		pipeline[i] = cmd
+	}
+	l.LogPipeline(l.CtxLogger(ctx), pipeline, elapsed)
+	return nil
+}
+
+// LogCmd
+//
+//	@Description: 打印单条命令的 redis trace 日志并记录统计， go-redis v8 和 v9 的 hook 共用
+//	@receiver l
+//	@param logger 通过 CtxLogger 创建， caller 为调用 LogCmd 的上一层
+//	@param cmd
+//	@param err 命令的错误
+//	@param elapsed 命令耗时
+func (l RedisLogger) LogCmd(logger *zap.Logger, cmd RedisCmd, err error, elapsed time.Duration) {
+	logger = logger.WithOptions(zap.AddCallerSkip(1))
+	l.observe(cmd, err, elapsed)
+	fields := []zap.Field{zap.String("command", cmd.FullName()), zap.String("args", l.formatter.Format(cmd)), zap.Float64("latency_ms", durationMs(elapsed))}
+	level := zap.InfoLevel
+	switch {
+	case err != nil:
+		level = l.errLevel(err)
+		fields = append(fields, zap.Error(err))
+	case elapsed > l.slowThreshold:
+		level = zap.WarnLevel
+	}
+	logger.Log(level, "redis trace", fields...)
+}
+
+// LogPipeline
+//
+//	@Description: 打印 pipeline 的 redis trace 日志，包含每条命令的状态，并记录统计， go-redis v8 和 v9 的 hook 共用
+//	@receiver l
+//	@param logger 通过 CtxLogger 创建， caller 为调用 LogPipeline 的上一层
+//	@param cmds
+//	@param elapsed pipeline 耗时
+func (l RedisLogger) LogPipeline(logger *zap.Logger, cmds []RedisCmd, elapsed time.Duration) {
+	logger = logger.WithOptions(zap.AddCallerSkip(1))
+	level := zap.InfoLevel
+	if elapsed > l.slowThreshold {
+		level = zap.WarnLevel
+	}
 	pipelineArgs := make([]string, 0, len(cmds))
+	pipelineStatus := make([]string, 0, len(cmds))
 	pipelineErrs := make([]error, 0, len(cmds))
-	for _, cmd := range cmds {
-		pipelineArgs = append(pipelineArgs, cmd.String())
-		if err := cmd.Err(); err != nil {
-			pipelineErrs = append(pipelineErrs, err)
+	transaction := isRedisTransaction(cmds)
+	for i, cmd := range cmds {
+		err := cmd.Err()
+		if !transaction || (i > 0 && i < len(cmds)-1) {
+			// pipeline 中的命令按平均耗时统计，不统计事务的 MULTI 和 EXEC
+			l.observe(cmd, err, elapsed/time.Duration(len(cmds)))
+		}
+		args := l.formatter.Format(cmd)
+		pipelineArgs = append(pipelineArgs, args)
+		pipelineStatus = append(pipelineStatus, l.cmdStatus(err))
+		if err == nil {
+			continue
 		}
+		pipelineErrs = append(pipelineErrs, err)
+		cmdLevel := l.errLevel(err)
+		if cmdLevel > level {
+			level = cmdLevel
+		}
+		if l.pipelineErrorDetails {
+			logger.Log(cmdLevel, "redis pipeline cmd", zap.String("command", cmd.FullName()), zap.String("args", args), zap.Int("pipeline_index", i), zap.Error(err))
+		}
+	}
+	fields := []zap.Field{
+		zap.Strings("args", pipelineArgs),
+		zap.Bool("pipeline", true),
+		zap.Strings("status", pipelineStatus),
+		zap.Float64("latency_ms", durationMs(elapsed)),
+	}
+	if transaction {
+		fields = append(fields, zap.Bool("transaction", true))
 	}
 	if len(pipelineErrs) > 0 {
-		logger.Warn("redis trace", zap.Any("args", pipelineArgs), zap.Bool("pipeline", true), zap.Float64("latency_ms", cost), zap.Errors("errors", pipelineErrs))
-	} else {
-		logger.Info("redis trace", zap.Any("args", pipelineArgs), zap.Bool("pipeline", true), zap.Float64("latency_ms", cost))
+		fields = append(fields, zap.Errors("errors", pipelineErrs))
 	}
-	return nil
+	logger.Log(level, "redis trace", fields...)
+}
+
+// observe
+//
+//	@Description: 记录命令统计，没有设置 Stats 时忽略
+//	@receiver l
+//	@param cmd
+//	@param err 命令的错误
+//	@param latency
+func (l RedisLogger) observe(cmd RedisCmd, err error, latency time.Duration) {
+	if l.stats == nil {
+		return
+	}
+	l.stats.Observe(cmd.FullName(), RedisCmdKey(cmd), err != nil && !l.isNil(err), latency)
 }
 
-// getCost
+// isNil
 //
-//	@Description: 获取命令执行耗时
+//	@Description: err 是否为 redis.Nil
 //	@receiver l
+//	@param err
+//	@return bool
+func (l RedisLogger) isNil(err error) bool {
+	return l.nilErr != nil && errors.Is(err, l.nilErr)
+}
+
+// errLevel
+//
+//	@Description: 获取命令错误的日志级别， redis.Nil 使用 NilErrLevel ，其他错误使用 Error
+//	@receiver l
+//	@param err
+//	@return zapcore.Level
+func (l RedisLogger) errLevel(err error) zapcore.Level {
+	if l.isNil(err) {
+		if level, err := zapcore.ParseLevel(l.nilErrLevel); err == nil {
+			return level
+		}
+	}
+	return zap.ErrorLevel
+}
+
+// cmdStatus
+//
+//	@Description: 获取 pipeline 中单个命令的执行状态： ok 、 nil 或 error
+//	@receiver l
+//	@param err
+//	@return string
+func (l RedisLogger) cmdStatus(err error) string {
+	switch {
+	case err == nil:
+		return "ok"
+	case l.isNil(err):
+		return "nil"
+	default:
+		return "error"
+	}
+}
+
+// isRedisTransaction
+//
+//	@Description: pipeline 是否为 MULTI/EXEC 事务
+//	@param cmds
+//	@return bool
+func isRedisTransaction(cmds []RedisCmd) bool {
+	return len(cmds) >= 2 && cmds[0].Name() == "multi" && cmds[len(cmds)-1].Name() == "exec"
+}
+
+// redisStart 单条命令（或单个 pipeline）的开始时间，以及调用方传入的原始 context
+type redisStart struct {
+	parent context.Context
+	start  time.Time
+}
+
+// withRedisStart
+//
+//	@Description: 为每条命令派生新的 context 保存开始时间，同一个 context （包括 gin.Context ）上并发执行的命令互不影响
+//	@param ctx
+//	@return context.Context
+func withRedisStart(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ctxRedisStartKey, &redisStart{parent: ctx, start: time.Now()})
+}
+
+// redisElapsed
+//
+//	@Description: 获取 BeforeProcess 传入的原始 context 和命令执行耗时，context 中没有开始时间时耗时为 0
 //	@param ctx
-//	@return cost
-func (l RedisLogger) getCost(ctx context.Context) (cost float64) {
-	var startTime time.Time
-	if gc, ok := ctx.(*gin.Context); ok {
-		// set start time in gin.Context
-		startTime = gc.GetTime(string(ctxRedisStartKey))
-	} else {
-		startTime = ctx.Value(ctxRedisStartKey).(time.Time)
-	}
-	if !startTime.IsZero() {
-		cost = time.Since(startTime).Seconds() * 1e3
-	}
-	return
+//	@return context.Context
+//	@return time.Duration
+func redisElapsed(ctx context.Context) (context.Context, time.Duration) {
+	rs, ok := ctx.Value(ctxRedisStartKey).(*redisStart)
+	if !ok || rs == nil {
+		return ctx, 0
+	}
+	return rs.parent, time.Since(rs.start)
+}
+
+// durationMs
+//
+//	@Description: 将耗时转换为毫秒
+//	@param d
+//	@return float64
+func durationMs(d time.Duration) float64 {
+	return d.Seconds() * 1e3
 }
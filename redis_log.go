@@ -0,0 +1,145 @@
+package logit
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// RedisLogging 实现 go-redis 的 internal.Logging 接口，将 go-redis 内部日志（连接池、 pubsub 、 sentinel 等）打印到 logit
+// v8 和 v9 的接口相同，都可以通过 redis.SetLogger 设置
+type RedisLogging struct {
+	// 为 nil 时使用 CtxLogger(ctx)
+	logger *zap.Logger
+	// 没有识别到日志级别时使用的级别
+	level zapcore.Level
+}
+
+// NewRedisLogging
+//
+//	@Description: 创建 go-redis 内部日志的适配器，日志带有 ctx 中的 trace id ，根据日志内容前缀识别日志级别
+//	@param logger 为 nil 时使用 CtxLogger(ctx)
+//	@param level 没有识别到日志级别时使用的级别， go-redis 内部日志大多是连接异常，建议使用 Warn
+//	@return *RedisLogging
+func NewRedisLogging(logger *zap.Logger, level zapcore.Level) *RedisLogging {
+	return &RedisLogging{logger: logger, level: level}
+}
+
+// Printf
+//
+//	@Description: 实现 go-redis internal.Logging 接口方法
+//	@receiver l
+//	@param ctx
+//	@param format
+//	@param v
+func (l *RedisLogging) Printf(ctx context.Context, format string, v ...interface{}) {
+	level, msg := detectLogLevel(strings.TrimRight(fmt.Sprintf(format, v...), "\r\n"), l.level)
+	var logger *zap.Logger
+	if l.logger == nil {
+		logger = CtxLogger(ctx)
+	} else {
+		_, logger = NewCtxLogger(ctx, l.logger, "")
+	}
+	// caller 为 go-redis 内部打印日志的位置
+	if ce := logger.WithOptions(zap.AddCallerSkip(1)).Check(level, msg); ce != nil {
+		ce.Write()
+	}
+}
+
+// RedisStdLogging 与 go-redis 默认 logger 相同，输出到 stderr ，用于恢复 go-redis 的默认 logger
+type RedisStdLogging struct {
+	log *log.Logger
+}
+
+// NewRedisStdLogging
+//
+//	@Description: 创建与 go-redis 默认 logger 相同的 logger
+//	@return *RedisStdLogging
+func NewRedisStdLogging() *RedisStdLogging {
+	return &RedisStdLogging{log: log.New(os.Stderr, "redis: ", log.LstdFlags|log.Lshortfile)}
+}
+
+// Printf
+//
+//	@Description: 实现 go-redis internal.Logging 接口方法
+//	@receiver l
+//	@param ctx
+//	@param format
+//	@param v
+func (l *RedisStdLogging) Printf(ctx context.Context, format string, v ...interface{}) {
+	_ = l.log.Output(2, fmt.Sprintf(format, v...))
+}
+
+// RedisInternalLogging go-redis 内部日志的接口， v8 和 v9 的 internal.Logging 相同
+type RedisInternalLogging interface {
+	Printf(ctx context.Context, format string, v ...interface{})
+}
+
+// RedisLogRedirector 重定向 go-redis 的内部日志，并记录当前设置的内部 logger ， go-redis 没有提供获取 logger 的方法，需要自己记录
+// go-redis 的每个版本使用一个 RedisLogRedirector
+type RedisLogRedirector struct {
+	mutex     sync.Mutex
+	logging   RedisInternalLogging
+	setLogger func(logging RedisInternalLogging)
+}
+
+// NewRedisLogRedirector
+//
+//	@Description: 创建 RedisLogRedirector ，初始的内部 logger 与 go-redis 默认 logger 相同
+//	@param setLogger 设置 go-redis 内部 logger 的函数，例如 func(l RedisInternalLogging) { redis.SetLogger(l) }
+//	@return *RedisLogRedirector
+func NewRedisLogRedirector(setLogger func(logging RedisInternalLogging)) *RedisLogRedirector {
+	return &RedisLogRedirector{logging: NewRedisStdLogging(), setLogger: setLogger}
+}
+
+// Redirect
+//
+//	@Description: 将 go-redis 的内部日志重定向到 logit ，没有识别到日志级别时使用 Warn 级别
+//	@receiver r
+//	@param logger 为 nil 时使用 CtxLogger(ctx)
+//	@return func() 调用它可以恢复 go-redis 上一次的内部 logger
+func (r *RedisLogRedirector) Redirect(logger *zap.Logger) func() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	prevLogging := r.logging
+	r.logging = NewRedisLogging(logger, zap.WarnLevel)
+	r.setLogger(r.logging)
+	return func() {
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		r.logging = prevLogging
+		r.setLogger(prevLogging)
+	}
+}
+
+// Logging
+//
+//	@Description: 获取当前设置的 go-redis 内部 logger
+//	@receiver r
+//	@return RedisInternalLogging
+func (r *RedisLogRedirector) Logging() RedisInternalLogging {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.logging
+}
+
+// go-redis v8 的内部日志重定向
+var redisLogRedirector = NewRedisLogRedirector(func(logging RedisInternalLogging) {
+	redis.SetLogger(logging)
+})
+
+// RedirectRedisLog
+//
+//	@Description: 将 go-redis v8 的内部日志重定向到 logit ，没有识别到日志级别时使用 Warn 级别
+//	@param logger 为 nil 时使用 CtxLogger(ctx)
+//	@return func() 调用它可以恢复 go-redis 上一次的内部 logger
+func RedirectRedisLog(logger *zap.Logger) func() {
+	return redisLogRedirector.Redirect(logger)
+}
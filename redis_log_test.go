@@ -0,0 +1,43 @@
+package logit
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+)
+
+func TestRedisLogging(t *testing.T) {
+	logfile := filepath.Join(t.TempDir(), "redis.log")
+	logger, _ := NewLogger(Options{Format: "json", OutputPaths: []string{logfile}})
+	ctx := context.WithValue(context.Background(), TraceIDKeyName, "trace-redis")
+	NewRedisLogging(logger, zap.WarnLevel).Printf(ctx, "redis: discarding bad conn: %s", "eof")
+	NewRedisLogging(logger, zap.WarnLevel).Printf(ctx, "[ERROR] %s", "boom")
+
+	logs := readTestLogs(t, logfile)
+	if len(logs) != 2 {
+		t.Fatal("invalid logs", logs)
+	}
+	if logs[0]["level"] != "WARN" || logs[0]["msg"] != "redis: discarding bad conn: eof" || logs[0]["trace_id"] != "trace-redis" || logs[1]["level"] != "ERROR" {
+		t.Error("invalid redis logs", logs)
+	}
+}
+
+func TestRedirectRedisLog(t *testing.T) {
+	undo := RedirectRedisLog(nil)
+	if _, ok := redisLogRedirector.Logging().(*RedisLogging); !ok {
+		t.Fatal("redis logging should be redirected")
+	}
+	undoNested := RedirectRedisLog(zap.NewNop())
+	undoNested()
+	if l, ok := redisLogRedirector.Logging().(*RedisLogging); !ok || l.logger != nil {
+		t.Error("undo should restore previous redis logging")
+	}
+	undo()
+	if _, ok := redisLogRedirector.Logging().(*RedisStdLogging); !ok {
+		t.Error("undo should restore default redis logging")
+	}
+	redis.SetLogger(redisLogRedirector.Logging())
+}
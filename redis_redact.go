@@ -0,0 +1,316 @@
+package logit
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// 被隐藏的 redis 参数和返回值
+	redisRedactedValue = "[redacted]"
+	// redis 参数和返回值超出最大记录长度被截断时追加的标记
+	redisTruncatedMarker = "...(truncated)"
+)
+
+// redisRedacted 被隐藏的参数，格式化时不截断
+type redisRedacted struct{}
+
+// redisKeylessCommands 不包含 key 的 redis 命令
+var redisKeylessCommands = map[string]bool{
+	"acl": true, "auth": true, "bgrewriteaof": true, "bgsave": true, "client": true, "cluster": true,
+	"command": true, "config": true, "dbsize": true, "debug": true, "discard": true, "echo": true,
+	"exec": true, "failover": true, "flushall": true, "flushdb": true, "function": true, "hello": true,
+	"info": true, "keys": true, "lastsave": true, "latency": true, "lolwut": true, "module": true,
+	"monitor": true, "multi": true, "ping": true, "psubscribe": true, "pubsub": true, "punsubscribe": true,
+	"quit": true, "randomkey": true, "readonly": true, "readwrite": true, "replicaof": true, "reset": true,
+	"role": true, "save": true, "scan": true, "script": true, "select": true, "shutdown": true,
+	"slaveof": true, "slowlog": true, "subscribe": true, "swapdb": true, "sync": true, "time": true,
+	"unsubscribe": true, "unwatch": true, "wait": true,
+}
+
+// RedisCmd 格式化和打印日志需要的 redis 命令方法， go-redis v8 和 v9 的 Cmder 都实现了该接口
+type RedisCmd interface {
+	Name() string
+	FullName() string
+	Args() []interface{}
+	String() string
+	Err() error
+}
+
+// RedisCmdFormatter 格式化日志中记录的 redis 命令参数和返回值，支持隐藏敏感信息和截断
+// AUTH 、 HELLO 、 MIGRATE 的认证信息和 CONFIG SET 的密码始终隐藏
+type RedisCmdFormatter struct {
+	keyPatterns       []string
+	maxArgLength      int
+	maxReplyLength    int
+	onlyCommandAndKey bool
+}
+
+// NewRedisCmdFormatter
+//
+//	@Description: 使用 RedisLoggerOptions 中的 RedactKeyPatterns 、 MaxArgLength 、 MaxReplyLength 、 OnlyCommandAndKey 创建 RedisCmdFormatter
+//	@param opt
+//	@return *RedisCmdFormatter
+//	@return error key 模式不合法时返回 path.ErrBadPattern
+func NewRedisCmdFormatter(opt RedisLoggerOptions) (*RedisCmdFormatter, error) {
+	for _, pattern := range opt.RedactKeyPatterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, err
+		}
+	}
+	return &RedisCmdFormatter{
+		keyPatterns:       opt.RedactKeyPatterns,
+		maxArgLength:      opt.MaxArgLength,
+		maxReplyLength:    opt.MaxReplyLength,
+		onlyCommandAndKey: opt.OnlyCommandAndKey,
+	}, nil
+}
+
+// Format
+//
+//	@Description: 格式化 redis 命令，格式与 cmd.String() 相同，为 nil 时直接返回 cmd.String()
+//	@receiver f
+//	@param cmd
+//	@return string
+func (f *RedisCmdFormatter) Format(cmd RedisCmd) string {
+	if f == nil {
+		return cmd.String()
+	}
+	name := cmd.Name()
+	args := cmd.Args()
+	if len(args) == 0 {
+		return cmd.String()
+	}
+	keyPos := redisKeyPos(name, args)
+	if f.onlyCommandAndKey {
+		if keyPos > 0 {
+			return redisArgString(args[0]) + " " + f.truncate(redisArgString(args[keyPos]), f.maxArgLength)
+		}
+		return redisArgString(args[0])
+	}
+
+	redacted, hideReply := f.redact(name, args, keyPos)
+	var b strings.Builder
+	for i, arg := range redacted {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		if _, ok := arg.(redisRedacted); ok {
+			b.WriteString(redisRedactedValue)
+			continue
+		}
+		b.WriteString(f.truncate(redisArgString(arg), f.maxArgLength))
+	}
+	if cmd.Err() != nil {
+		// 错误信息不隐藏，由日志的 error 字段记录
+		return b.String() + ": " + cmd.Err().Error()
+	}
+	if reply, ok := redisReply(cmd, args); ok {
+		b.WriteString(": ")
+		if hideReply {
+			b.WriteString(redisRedactedValue)
+		} else {
+			b.WriteString(f.truncate(reply, f.maxReplyLength))
+		}
+	}
+	return b.String()
+}
+
+// RedisCmdKey
+//
+//	@Description: 获取命令的第一个 key ，没有 key 时返回空字符串
+//	@param cmd
+//	@return string
+func RedisCmdKey(cmd RedisCmd) string {
+	args := cmd.Args()
+	if pos := redisKeyPos(cmd.Name(), args); pos > 0 {
+		return redisArgString(args[pos])
+	}
+	return ""
+}
+
+// redact
+//
+//	@Description: 隐藏认证信息和匹配 key 模式的参数值，不修改原参数
+//	@receiver f
+//	@param name 小写的命令名称
+//	@param args
+//	@param keyPos 第一个 key 的位置，没有 key 时为 0
+//	@return []interface{} 隐藏后的参数
+//	@return bool 是否需要隐藏返回值
+func (f *RedisCmdFormatter) redact(name string, args []interface{}, keyPos int) ([]interface{}, bool) {
+	redacted := make([]interface{}, len(args))
+	copy(redacted, args)
+	switch name {
+	case "auth":
+		for i := 1; i < len(redacted); i++ {
+			redacted[i] = redisRedacted{}
+		}
+		return redacted, false
+	case "hello":
+		// HELLO protover AUTH username password SETNAME name
+		for i := 1; i+2 < len(redacted); i++ {
+			if strings.EqualFold(redisArgString(redacted[i]), "auth") {
+				redacted[i+2] = redisRedacted{}
+			}
+		}
+		return redacted, false
+	case "migrate":
+		// MIGRATE host port key destination-db timeout [COPY] [REPLACE] [AUTH password | AUTH2 username password] [KEYS key ...]
+		for i := 6; i < len(redacted); i++ {
+			switch strings.ToLower(redisArgString(redacted[i])) {
+			case "auth":
+				if i+1 < len(redacted) {
+					redacted[i+1] = redisRedacted{}
+				}
+				i++
+			case "auth2":
+				for j := i + 1; j <= i+2 && j < len(redacted); j++ {
+					redacted[j] = redisRedacted{}
+				}
+				i += 2
+			case "keys":
+				return redacted, false
+			}
+		}
+		return redacted, false
+	case "config":
+		// CONFIG SET requirepass password
+		if len(redacted) > 3 && strings.EqualFold(redisArgString(redacted[1]), "set") {
+			for i := 2; i+1 < len(redacted); i += 2 {
+				switch strings.ToLower(redisArgString(redacted[i])) {
+				case "requirepass", "masterauth":
+					redacted[i+1] = redisRedacted{}
+				}
+			}
+		}
+		return redacted, false
+	case "mset", "msetnx":
+		// MSET key value [key value ...] 只隐藏匹配的 key 对应的值
+		for i := 1; i+1 < len(redacted); i += 2 {
+			if f.matchKey(redisArgString(redacted[i])) {
+				redacted[i+1] = redisRedacted{}
+			}
+		}
+		return redacted, false
+	}
+	if keyPos <= 0 || !f.matchKey(redisArgString(args[keyPos])) {
+		return redacted, false
+	}
+	for i := keyPos + 1; i < len(redacted); i++ {
+		redacted[i] = redisRedacted{}
+	}
+	return redacted, true
+}
+
+// matchKey
+//
+//	@Description: key 是否匹配需要隐藏的 key 模式
+//	@receiver f
+//	@param key
+//	@return bool
+func (f *RedisCmdFormatter) matchKey(key string) bool {
+	for _, pattern := range f.keyPatterns {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// truncate
+//
+//	@Description: 截断超出 max 长度的字符串， max 小于等于 0 时不截断
+//	@receiver f
+//	@param s
+//	@param max
+//	@return string
+func (f *RedisCmdFormatter) truncate(s string, max int) string {
+	if max <= 0 || len(s) <= max {
+		return s
+	}
+	// 不截断多字节字符，退回到字符的起始位置
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return s[:max] + redisTruncatedMarker
+}
+
+// redisKeyPos
+//
+//	@Description: 获取命令第一个 key 的位置，没有 key 时返回 0
+//	@param name 小写的命令名称
+//	@param args
+//	@return int
+func redisKeyPos(name string, args []interface{}) int {
+	if len(args) < 2 || redisKeylessCommands[name] {
+		return 0
+	}
+	switch name {
+	case "eval", "evalsha", "eval_ro", "evalsha_ro", "fcall", "fcall_ro":
+		// EVAL script numkeys key [key ...] arg [arg ...]
+		if len(args) > 3 && redisArgString(args[2]) != "0" {
+			return 3
+		}
+		return 0
+	case "memory":
+		// MEMORY USAGE key
+		if len(args) > 2 && strings.EqualFold(redisArgString(args[1]), "usage") {
+			return 2
+		}
+		return 0
+	}
+	return 1
+}
+
+// redisArgString
+//
+//	@Description: 按照 go-redis 的格式将参数转换为字符串
+//	@param arg
+//	@return string
+func redisArgString(arg interface{}) string {
+	switch v := arg.(type) {
+	case nil:
+		return "<nil>"
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 64)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// redisReply
+//
+//	@Description: 从 cmd.String() 中获取返回值
+//	@param cmd
+//	@param args
+//	@return string
+//	@return bool 没有返回值时返回 false
+func redisReply(cmd RedisCmd, args []interface{}) (string, bool) {
+	var b strings.Builder
+	for i, arg := range args {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(redisArgString(arg))
+	}
+	b.WriteString(": ")
+	prefix := b.String()
+	s := cmd.String()
+	if !strings.HasPrefix(s, prefix) {
+		return "", false
+	}
+	return s[len(prefix):], true
+}
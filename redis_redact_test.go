@@ -0,0 +1,151 @@
+package logit
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-redis/redis/v8"
+)
+
+func TestRedisCmdFormatter(t *testing.T) {
+	ctx := context.Background()
+	formatter, err := NewRedisCmdFormatter(RedisLoggerOptions{
+		RedactKeyPatterns: []string{"session:*"},
+		MaxArgLength:      8,
+		MaxReplyLength:    4,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := redis.NewStringCmd(ctx, "get", "session:1")
+	get.SetVal(`{"user":1}`)
+	set := redis.NewStatusCmd(ctx, "set", "session:1", `{"user":1}`, "ex", 10)
+	set.SetVal("OK")
+	mset := redis.NewStatusCmd(ctx, "mset", "a", 1, "session:2", "token")
+	mset.SetVal("OK")
+	long := redis.NewStringCmd(ctx, "set", "a", "0123456789")
+	long.SetVal("OKOKOK")
+	failed := redis.NewStatusCmd(ctx, "set", "session:1", "v")
+	failed.SetErr(errors.New("READONLY"))
+	cases := []struct {
+		cmd  RedisCmd
+		want string
+	}{
+		{redis.NewStatusCmd(ctx, "auth", "user", "password"), "auth [redacted] [redacted]: "},
+		{redis.NewSliceCmd(ctx, "hello", 3, "AUTH", "user", "password", "SETNAME", "app"), "hello 3 AUTH user [redacted] SETNAME app: []"},
+		{redis.NewStatusCmd(ctx, "config", "set", "requirepass", "password"), "config set requirep...(truncated) [redacted]: "},
+		{get, "get session:...(truncated): [redacted]"},
+		{set, "set session:...(truncated) [redacted] [redacted] [redacted]: [redacted]"},
+		{mset, "mset a 1 session:...(truncated) [redacted]: OK"},
+		{long, "set a 01234567...(truncated): OKOK...(truncated)"},
+		{failed, "set session:...(truncated) [redacted]: READONLY"},
+	}
+	for _, c := range cases {
+		if got := formatter.Format(c.cmd); got != c.want {
+			t.Errorf("Format(%v) = %q, want %q", c.cmd.Args(), got, c.want)
+		}
+	}
+}
+
+func TestRedisCmdFormatterMigrate(t *testing.T) {
+	ctx := context.Background()
+	formatter, _ := NewRedisCmdFormatter(RedisLoggerOptions{})
+	cases := []struct {
+		cmd  RedisCmd
+		want string
+	}{
+		{redis.NewStatusCmd(ctx, "migrate", "127.0.0.1", 6379, "a", 0, 1000, "COPY", "AUTH", "password"), "migrate 127.0.0.1 6379 a 0 1000 COPY AUTH [redacted]: "},
+		{redis.NewStatusCmd(ctx, "migrate", "127.0.0.1", 6379, "", 0, 1000, "AUTH2", "user", "password", "KEYS", "auth", "b"), "migrate 127.0.0.1 6379  0 1000 AUTH2 [redacted] [redacted] KEYS auth b: "},
+		{redis.NewStatusCmd(ctx, "migrate", "127.0.0.1", 6379, "", 0, 1000, "KEYS", "auth", "b"), "migrate 127.0.0.1 6379  0 1000 KEYS auth b: "},
+	}
+	for _, c := range cases {
+		if got := formatter.Format(c.cmd); got != c.want {
+			t.Errorf("Format(%v) = %q, want %q", c.cmd.Args(), got, c.want)
+		}
+	}
+}
+
+func TestRedisCmdFormatterTruncateUTF8(t *testing.T) {
+	ctx := context.Background()
+	formatter, _ := NewRedisCmdFormatter(RedisLoggerOptions{MaxArgLength: 4, MaxReplyLength: 5})
+	set := redis.NewStringCmd(ctx, "set", "a", "中文参数")
+	set.SetVal("返回值")
+	got := formatter.Format(set)
+	if !utf8.ValidString(got) || got != "set a 中...(truncated): 返...(truncated)" {
+		t.Error("truncate should not split utf-8 characters", got)
+	}
+}
+
+func TestRedisArgString(t *testing.T) {
+	now := time.Now()
+	// 与 go-redis 的参数格式保持一致
+	for _, arg := range []interface{}{nil, "s", []byte("b"), 1, int64(-2), uint8(3), float32(0.1), 1e21, true, now, time.Second} {
+		if got, want := redisArgString(arg), redis.NewCmd(context.Background(), arg).String(); got != want {
+			t.Errorf("redisArgString(%#v) = %q, want %q", arg, got, want)
+		}
+	}
+}
+
+func TestRedisCmdFormatterOnlyCommandAndKey(t *testing.T) {
+	ctx := context.Background()
+	formatter, _ := NewRedisCmdFormatter(RedisLoggerOptions{OnlyCommandAndKey: true})
+	set := redis.NewStatusCmd(ctx, "set", "a", "secret")
+	set.SetVal("OK")
+	if got := formatter.Format(set); got != "set a" {
+		t.Error("invalid format", got)
+	}
+	if got := formatter.Format(redis.NewStatusCmd(ctx, "auth", "password")); got != "auth" {
+		t.Error("invalid format", got)
+	}
+	if got := formatter.Format(redis.NewCmd(ctx, "eval", "return 1", 1, "b", "arg")); got != "eval b" {
+		t.Error("invalid format", got)
+	}
+}
+
+func TestRedisCmdFormatterNil(t *testing.T) {
+	var formatter *RedisCmdFormatter
+	cmd := redis.NewStatusCmd(context.Background(), "auth", "password")
+	if got := formatter.Format(cmd); got != cmd.String() {
+		t.Error("nil formatter should use cmd.String()", got)
+	}
+	if _, err := NewRedisCmdFormatter(RedisLoggerOptions{RedactKeyPatterns: []string{"["}}); err == nil {
+		t.Error("invalid pattern should return error")
+	}
+}
+
+func TestRedisLoggerRedact(t *testing.T) {
+	logfile := filepath.Join(t.TempDir(), "redis.log")
+	logger, err := NewRedisLogger(RedisLoggerOptions{
+		OutputPaths:       []string{logfile},
+		DisableCaller:     true,
+		RedactKeyPatterns: []string{"session:*"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	cmd := redis.NewStatusCmd(ctx, "set", "session:1", "secret")
+	cmd.SetVal("OK")
+	cmdCtx, _ := logger.BeforeProcess(ctx, cmd)
+	logger.AfterProcess(cmdCtx, cmd)
+	auth := redis.NewStatusCmd(ctx, "auth", "password")
+	cmds := []redis.Cmder{auth}
+	cmdCtx, _ = logger.BeforeProcessPipeline(ctx, cmds)
+	logger.AfterProcessPipeline(cmdCtx, cmds)
+
+	logs := readTestLogs(t, logfile)
+	if len(logs) != 2 {
+		t.Fatal("invalid logs count", logs)
+	}
+	if logs[0]["args"] != "set session:1 [redacted]: [redacted]" {
+		t.Error("value should be redacted", logs[0])
+	}
+	if args := logs[1]["args"].([]interface{}); strings.Contains(args[0].(string), "password") {
+		t.Error("password should be redacted", logs[1])
+	}
+}
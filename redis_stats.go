@@ -0,0 +1,430 @@
+package logit
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// 默认统计窗口长度
+	defaultRedisStatsWindow = time.Minute
+	// 默认统计窗口分片数量
+	defaultRedisStatsSlots = 6
+	// 默认 key 前缀分隔符
+	defaultRedisStatsPrefixSeparator = ":"
+	// 默认每个窗口分片最多记录的 key 数量
+	defaultRedisStatsMaxKeys = 10000
+	// 默认最多记录的 key 前缀数量
+	defaultRedisStatsMaxPrefixes = 1000
+	// 默认 summary 中最多记录的热点 key 数量
+	defaultRedisStatsMaxHotKeys = 10
+	// 超出 key 前缀数量上限后使用的前缀
+	redisStatsOtherPrefix = "other"
+	// 没有 key 的命令使用的前缀
+	redisStatsNoKeyPrefix = "-"
+)
+
+// 耗时直方图分桶上限 (毫秒)，用于计算耗时分位数
+var redisStatsBuckets = []float64{.1, .25, .5, 1, 2.5, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+// RedisStatsConfig RedisStats 支持的配置项字段定义
+type RedisStatsConfig struct {
+	// 滑动窗口长度，统计最近 Window 时间内的命令
+	// Optional. Default value is 1m
+	Window time.Duration
+	// 滑动窗口分片数量，窗口按 Window/Slots 的粒度滑动
+	// Optional. Default value is 6
+	Slots int
+	// 计算 key 前缀的函数，默认取第一个 PrefixSeparator 之前的部分，如 user:1 的前缀为 user
+	// Optional.
+	KeyPrefix func(key string) string
+	// 默认 KeyPrefix 使用的分隔符
+	// Optional. Default value is :
+	PrefixSeparator string
+	// 热点 key 阈值，窗口内访问次数达到该值的 key 在 summary 中标记为热点 key ，为 0 时不统计单个 key
+	// Optional.
+	HotKeyThreshold int64
+	// 每个窗口分片最多记录的 key 数量，超出后新的 key 不再统计
+	// Optional. Default value is 10000
+	MaxKeys int
+	// 最多记录的 key 前缀数量，超出后计入 other 前缀
+	// Optional. Default value is 1000
+	MaxPrefixes int
+	// 定期打印 summary 日志的间隔，为 0 时使用 Window ，小于 0 时不打印
+	// Optional.
+	LogInterval time.Duration
+}
+
+// RedisStatsEntry 单个命令或 key 前缀在窗口内的统计， redis.Nil 不计为错误
+type RedisStatsEntry struct {
+	Name      string  `json:"name"`
+	Count     int64   `json:"count"`
+	Errors    int64   `json:"errors"`
+	ErrorRate float64 `json:"error_rate"`
+	// 耗时分位数 (毫秒)
+	P50 float64 `json:"p50_ms"`
+	P95 float64 `json:"p95_ms"`
+	P99 float64 `json:"p99_ms"`
+}
+
+// RedisHotKey 窗口内访问次数达到阈值的 key
+type RedisHotKey struct {
+	Key   string `json:"key"`
+	Count int64  `json:"count"`
+}
+
+// RedisStatsSummary 窗口内的命令统计，按访问次数从大到小排序
+type RedisStatsSummary struct {
+	Commands []RedisStatsEntry `json:"commands"`
+	Prefixes []RedisStatsEntry `json:"prefixes"`
+	HotKeys  []RedisHotKey     `json:"hot_keys,omitempty"`
+}
+
+// redisStatsGroup 单个命令或 key 前缀在一个窗口分片内的计数和耗时直方图
+type redisStatsGroup struct {
+	count   int64
+	errors  int64
+	buckets []int64
+}
+
+// add
+//
+//	@Description: 合并另一个分组的计数
+//	@receiver g
+//	@param other
+func (g *redisStatsGroup) add(other *redisStatsGroup) {
+	g.count += other.count
+	g.errors += other.errors
+	for i := range g.buckets {
+		g.buckets[i] += other.buckets[i]
+	}
+}
+
+// quantile
+//
+//	@Description: 根据直方图估算耗时分位数 (毫秒)，在分桶内线性插值，超出最大分桶时返回最大分桶上限
+//	@receiver g
+//	@param q 0~1
+//	@return float64
+func (g *redisStatsGroup) quantile(q float64) float64 {
+	if g.count == 0 {
+		return 0
+	}
+	rank := q * float64(g.count)
+	var cumulative int64
+	for i, n := range g.buckets {
+		if n == 0 || float64(cumulative+n) < rank {
+			cumulative += n
+			continue
+		}
+		if i == len(redisStatsBuckets) {
+			return redisStatsBuckets[len(redisStatsBuckets)-1]
+		}
+		lower := 0.0
+		if i > 0 {
+			lower = redisStatsBuckets[i-1]
+		}
+		return lower + (redisStatsBuckets[i]-lower)*(rank-float64(cumulative))/float64(n)
+	}
+	return redisStatsBuckets[len(redisStatsBuckets)-1]
+}
+
+// redisStatsSlot 滑动窗口的一个分片
+type redisStatsSlot struct {
+	// 分片序号，即分片开始时间除以分片长度
+	epoch    int64
+	commands map[string]*redisStatsGroup
+	prefixes map[string]*redisStatsGroup
+	keys     map[string]int64
+}
+
+// RedisStats 按命令和 key 前缀统计 redis 命令的次数、错误率和耗时分位数，并按阈值标记热点 key
+// 设置到 RedisLoggerOptions.Stats 后由 RedisLogger 记录，定期使用 RedisLogger 打印 redis stats 日志
+type RedisStats struct {
+	slotDuration    time.Duration
+	keyPrefix       func(key string) string
+	hotKeyThreshold int64
+	maxKeys         int
+	maxPrefixes     int
+	logInterval     time.Duration
+	now             func() time.Time
+
+	mu       sync.Mutex
+	slots    []*redisStatsSlot
+	prefixes map[string]struct{}
+
+	startOnce sync.Once
+	stopOnce  sync.Once
+	stop      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// NewRedisStats
+//
+//	@Description: 创建 redis 命令统计
+//	@param conf
+//	@return *RedisStats
+func NewRedisStats(conf RedisStatsConfig) *RedisStats {
+	if conf.Window <= 0 {
+		conf.Window = defaultRedisStatsWindow
+	}
+	if conf.Slots <= 0 {
+		conf.Slots = defaultRedisStatsSlots
+	}
+	if conf.PrefixSeparator == "" {
+		conf.PrefixSeparator = defaultRedisStatsPrefixSeparator
+	}
+	if conf.KeyPrefix == nil {
+		sep := conf.PrefixSeparator
+		conf.KeyPrefix = func(key string) string {
+			if i := strings.Index(key, sep); i > 0 {
+				return key[:i]
+			}
+			return key
+		}
+	}
+	if conf.MaxKeys <= 0 {
+		conf.MaxKeys = defaultRedisStatsMaxKeys
+	}
+	if conf.MaxPrefixes <= 0 {
+		conf.MaxPrefixes = defaultRedisStatsMaxPrefixes
+	}
+	if conf.LogInterval == 0 {
+		conf.LogInterval = conf.Window
+	}
+	// 窗口小于分片数时每个分片至少 1ns ，避免计算分片序号时除以 0
+	slotDuration := conf.Window / time.Duration(conf.Slots)
+	if slotDuration <= 0 {
+		slotDuration = 1
+	}
+	slots := make([]*redisStatsSlot, conf.Slots)
+	for i := range slots {
+		slots[i] = &redisStatsSlot{epoch: -1}
+	}
+	return &RedisStats{
+		slotDuration:    slotDuration,
+		keyPrefix:       conf.KeyPrefix,
+		hotKeyThreshold: conf.HotKeyThreshold,
+		maxKeys:         conf.MaxKeys,
+		maxPrefixes:     conf.MaxPrefixes,
+		logInterval:     conf.LogInterval,
+		now:             time.Now,
+		slots:           slots,
+		prefixes:        map[string]struct{}{},
+		stop:            make(chan struct{}),
+	}
+}
+
+// Observe
+//
+//	@Description: 记录一条命令
+//	@receiver s
+//	@param command 命令名称
+//	@param key 命令的第一个 key ，没有 key 时为空
+//	@param failed 命令是否执行失败， redis.Nil 不计为失败
+//	@param latency 命令耗时
+func (s *RedisStats) Observe(command, key string, failed bool, latency time.Duration) {
+	prefix := redisStatsNoKeyPrefix
+	if key != "" {
+		prefix = s.keyPrefix(key)
+	}
+	ms := durationMs(latency)
+	bucket := sort.SearchFloat64s(redisStatsBuckets, ms)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.prefixes[prefix]; !exists {
+		if len(s.prefixes) >= s.maxPrefixes {
+			prefix = redisStatsOtherPrefix
+		} else {
+			s.prefixes[prefix] = struct{}{}
+		}
+	}
+	slot := s.currentSlot()
+	for _, g := range []*redisStatsGroup{redisStatsGroupOf(slot.commands, command), redisStatsGroupOf(slot.prefixes, prefix)} {
+		g.count++
+		if failed {
+			g.errors++
+		}
+		g.buckets[bucket]++
+	}
+	if s.hotKeyThreshold <= 0 || key == "" {
+		return
+	}
+	if _, exists := slot.keys[key]; exists || len(slot.keys) < s.maxKeys {
+		slot.keys[key]++
+	}
+}
+
+// currentSlot
+//
+//	@Description: 获取当前时间所在的窗口分片，分片过期时清空，需要持有锁
+//	@receiver s
+//	@return *redisStatsSlot
+func (s *RedisStats) currentSlot() *redisStatsSlot {
+	epoch := s.now().UnixNano() / int64(s.slotDuration)
+	slot := s.slots[epoch%int64(len(s.slots))]
+	if slot.epoch != epoch {
+		slot.epoch = epoch
+		slot.commands = map[string]*redisStatsGroup{}
+		slot.prefixes = map[string]*redisStatsGroup{}
+		slot.keys = map[string]int64{}
+	}
+	return slot
+}
+
+// redisStatsGroupOf
+//
+//	@Description: 获取或创建分组
+//	@param groups
+//	@param name
+//	@return *redisStatsGroup
+func redisStatsGroupOf(groups map[string]*redisStatsGroup, name string) *redisStatsGroup {
+	g, exists := groups[name]
+	if !exists {
+		g = &redisStatsGroup{buckets: make([]int64, len(redisStatsBuckets)+1)}
+		groups[name] = g
+	}
+	return g
+}
+
+// Snapshot
+//
+//	@Description: 获取当前窗口内的统计
+//	@receiver s
+//	@return RedisStatsSummary
+func (s *RedisStats) Snapshot() RedisStatsSummary {
+	commands := map[string]*redisStatsGroup{}
+	prefixes := map[string]*redisStatsGroup{}
+	keys := map[string]int64{}
+	s.mu.Lock()
+	epoch := s.now().UnixNano() / int64(s.slotDuration)
+	for _, slot := range s.slots {
+		if slot.epoch < 0 || epoch-slot.epoch >= int64(len(s.slots)) {
+			continue
+		}
+		for name, g := range slot.commands {
+			redisStatsGroupOf(commands, name).add(g)
+		}
+		for name, g := range slot.prefixes {
+			redisStatsGroupOf(prefixes, name).add(g)
+		}
+		for key, n := range slot.keys {
+			keys[key] += n
+		}
+	}
+	s.mu.Unlock()
+
+	summary := RedisStatsSummary{
+		Commands: redisStatsEntries(commands),
+		Prefixes: redisStatsEntries(prefixes),
+	}
+	for key, n := range keys {
+		if n >= s.hotKeyThreshold {
+			summary.HotKeys = append(summary.HotKeys, RedisHotKey{Key: key, Count: n})
+		}
+	}
+	sort.Slice(summary.HotKeys, func(i, j int) bool {
+		if summary.HotKeys[i].Count != summary.HotKeys[j].Count {
+			return summary.HotKeys[i].Count > summary.HotKeys[j].Count
+		}
+		return summary.HotKeys[i].Key < summary.HotKeys[j].Key
+	})
+	if len(summary.HotKeys) > defaultRedisStatsMaxHotKeys {
+		summary.HotKeys = summary.HotKeys[:defaultRedisStatsMaxHotKeys]
+	}
+	return summary
+}
+
+// redisStatsEntries
+//
+//	@Description: 将分组转换为按访问次数从大到小排序的统计
+//	@param groups
+//	@return []RedisStatsEntry
+func redisStatsEntries(groups map[string]*redisStatsGroup) []RedisStatsEntry {
+	entries := make([]RedisStatsEntry, 0, len(groups))
+	for name, g := range groups {
+		entries = append(entries, RedisStatsEntry{
+			Name:      name,
+			Count:     g.count,
+			Errors:    g.errors,
+			ErrorRate: float64(g.errors) / float64(g.count),
+			P50:       g.quantile(.5),
+			P95:       g.quantile(.95),
+			P99:       g.quantile(.99),
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Count != entries[j].Count {
+			return entries[i].Count > entries[j].Count
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return entries
+}
+
+// LogSummary
+//
+//	@Description: 使用 logger 打印当前窗口的 redis stats 日志，存在热点 key 时使用 Warn 级别，窗口内没有命令时不打印
+//	@receiver s
+//	@param logger
+func (s *RedisStats) LogSummary(logger *zap.Logger) {
+	summary := s.Snapshot()
+	if len(summary.Commands) == 0 {
+		return
+	}
+	fields := []zap.Field{
+		zap.Float64("window", s.slotDuration.Seconds()*float64(len(s.slots))),
+		zap.Any("commands", summary.Commands),
+		zap.Any("prefixes", summary.Prefixes),
+	}
+	if len(summary.HotKeys) > 0 {
+		logger.Warn("redis stats", append(fields, zap.Any("hot_keys", summary.HotKeys))...)
+		return
+	}
+	logger.Info("redis stats", fields...)
+}
+
+// Start
+//
+//	@Description: 启动定期打印 summary 的 goroutine ，只会启动一次， LogInterval 小于 0 时不启动
+//	设置到 RedisLoggerOptions.Stats 时由 NewRedisLogger 使用 RedisLogger 的 logger 启动
+//	@receiver s
+//	@param logger
+func (s *RedisStats) Start(logger *zap.Logger) {
+	if s.logInterval < 0 {
+		return
+	}
+	s.startOnce.Do(func() {
+		logger = logger.WithOptions(zap.WithCaller(false))
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			ticker := time.NewTicker(s.logInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					s.LogSummary(logger)
+				case <-s.stop:
+					return
+				}
+			}
+		}()
+	})
+}
+
+// Stop
+//
+//	@Description: 停止定期打印 summary ，等待正在打印的日志完成
+//	@receiver s
+func (s *RedisStats) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+	s.wg.Wait()
+}
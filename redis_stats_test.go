@@ -0,0 +1,143 @@
+package logit
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+func TestRedisStatsWindow(t *testing.T) {
+	stats := NewRedisStats(RedisStatsConfig{Window: time.Minute, Slots: 6, HotKeyThreshold: 3, LogInterval: -1})
+	now := time.Unix(1000*60, 0)
+	stats.now = func() time.Time { return now }
+
+	for i := 0; i < 98; i++ {
+		stats.Observe("get", "user:1", false, 2*time.Millisecond)
+	}
+	stats.Observe("get", "user:2", true, 200*time.Millisecond)
+	stats.Observe("get", "user:3", true, 200*time.Millisecond)
+	stats.Observe("ping", "", false, time.Millisecond)
+	now = now.Add(30 * time.Second)
+	stats.Observe("set", "order:1", false, time.Millisecond)
+
+	summary := stats.Snapshot()
+	if len(summary.Commands) != 3 || summary.Commands[0].Name != "get" || summary.Commands[0].Count != 100 || summary.Commands[0].Errors != 2 {
+		t.Fatal("invalid commands", summary.Commands)
+	}
+	get := summary.Commands[0]
+	if get.ErrorRate != 0.02 || get.P50 <= 1 || get.P50 > 2.5 || get.P99 <= 100 || get.P99 > 250 {
+		t.Error("invalid get entry", get)
+	}
+	if len(summary.Prefixes) != 3 || summary.Prefixes[0].Name != "user" || summary.Prefixes[0].Count != 100 {
+		t.Error("invalid prefixes", summary.Prefixes)
+	}
+	if len(summary.HotKeys) != 1 || summary.HotKeys[0] != (RedisHotKey{Key: "user:1", Count: 98}) {
+		t.Error("invalid hot keys", summary.HotKeys)
+	}
+
+	// 窗口滑动后只保留最近 1 分钟的命令
+	now = now.Add(40 * time.Second)
+	summary = stats.Snapshot()
+	if len(summary.Commands) != 1 || summary.Commands[0].Name != "set" || len(summary.HotKeys) != 0 {
+		t.Error("expired slots should be dropped", summary)
+	}
+	now = now.Add(time.Minute)
+	if summary = stats.Snapshot(); len(summary.Commands) != 0 {
+		t.Error("all slots should be expired", summary)
+	}
+}
+
+func TestRedisStatsTinyWindow(t *testing.T) {
+	stats := NewRedisStats(RedisStatsConfig{Window: 5 * time.Nanosecond, Slots: 10, LogInterval: -1})
+	now := time.Unix(0, 100)
+	stats.now = func() time.Time { return now }
+	stats.Observe("get", "a", false, time.Millisecond)
+	if summary := stats.Snapshot(); len(summary.Commands) != 1 {
+		t.Error("invalid commands", summary.Commands)
+	}
+}
+
+func TestRedisStatsMaxPrefixes(t *testing.T) {
+	stats := NewRedisStats(RedisStatsConfig{MaxPrefixes: 1, MaxKeys: 1, HotKeyThreshold: 1, LogInterval: -1})
+	stats.Observe("get", "a:1", false, 0)
+	stats.Observe("get", "b:1", false, 0)
+	summary := stats.Snapshot()
+	if len(summary.Prefixes) != 2 || summary.Prefixes[0].Name != "a" || summary.Prefixes[1].Name != redisStatsOtherPrefix {
+		t.Error("invalid prefixes", summary.Prefixes)
+	}
+	if len(summary.HotKeys) != 1 || summary.HotKeys[0].Key != "a:1" {
+		t.Error("keys should be limited by MaxKeys", summary.HotKeys)
+	}
+}
+
+func TestRedisStatsLogSummary(t *testing.T) {
+	logfile := filepath.Join(t.TempDir(), "stats.log")
+	logger, _ := NewLogger(Options{Format: "json", OutputPaths: []string{logfile}})
+	stats := NewRedisStats(RedisStatsConfig{HotKeyThreshold: 2, LogInterval: -1})
+	stats.LogSummary(logger)
+	stats.Observe("get", "a", false, time.Millisecond)
+	stats.LogSummary(logger)
+	stats.Observe("get", "a", false, time.Millisecond)
+	stats.LogSummary(logger)
+
+	logs := readTestLogs(t, logfile)
+	if len(logs) != 2 {
+		t.Fatal("empty window should not be logged", logs)
+	}
+	if logs[0]["msg"] != "redis stats" || logs[0]["level"] != "INFO" || logs[0]["window"] != float64(60) || logs[0]["hot_keys"] != nil {
+		t.Error("invalid summary log", logs[0])
+	}
+	if logs[1]["level"] != "WARN" || len(logs[1]["hot_keys"].([]interface{})) != 1 {
+		t.Error("hot keys should be logged with warn level", logs[1])
+	}
+}
+
+func TestRedisLoggerStats(t *testing.T) {
+	server := miniredis.RunT(t)
+	logfile := filepath.Join(t.TempDir(), "redis.log")
+	stats := NewRedisStats(RedisStatsConfig{LogInterval: 10 * time.Millisecond})
+	defer stats.Stop()
+	logger, err := NewRedisLogger(RedisLoggerOptions{OutputPaths: []string{logfile}, Stats: stats})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+	client.AddHook(logger)
+	ctx := context.Background()
+	client.Set(ctx, "user:1", 1, 0)
+	client.Get(ctx, "user:2")
+	client.LPush(ctx, "user:1", 1)
+	tx := client.TxPipeline()
+	tx.Incr(ctx, "order:1")
+	_, _ = tx.Exec(ctx)
+
+	summary := stats.Snapshot()
+	if len(summary.Commands) != 4 {
+		t.Fatal("multi and exec should not be observed", summary.Commands)
+	}
+	for _, entry := range summary.Commands {
+		if entry.Name == "get" && entry.Errors != 0 || entry.Name == "lpush" && entry.Errors != 1 {
+			t.Error("invalid errors", entry)
+		}
+	}
+	if summary.Prefixes[0].Name != "user" || summary.Prefixes[0].Count != 3 {
+		t.Error("invalid prefixes", summary.Prefixes)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	stats.Stop()
+	var found bool
+	for _, log := range readTestLogs(t, logfile) {
+		if log["msg"] == "redis stats" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("summary should be logged periodically")
+	}
+}
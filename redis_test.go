@@ -9,9 +9,17 @@ package logit
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
 	"github.com/go-redis/redis/v8"
 )
 
@@ -51,3 +59,191 @@ func TestRedisLogger(t *testing.T) {
 	}
 	redisClient.Del(ctx, "b", "c")
 }
+
+func TestRedisLoggerSlowThreshold(t *testing.T) {
+	logfile := filepath.Join(t.TempDir(), "redis.log")
+	logger, err := NewRedisLogger(RedisLoggerOptions{
+		SlowThreshold: 20 * time.Millisecond,
+		OutputPaths:   []string{logfile},
+		// 直接调用 hook 时调用栈深度不足
+		DisableCaller: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	fast := redis.NewStatusCmd(ctx, "ping")
+	fastCtx, _ := logger.BeforeProcess(ctx, fast)
+	logger.AfterProcess(fastCtx, fast)
+
+	slow := redis.NewStatusCmd(ctx, "ping")
+	slowCtx, _ := logger.BeforeProcess(ctx, slow)
+	time.Sleep(30 * time.Millisecond)
+	logger.AfterProcess(slowCtx, slow)
+
+	pipe := []redis.Cmder{redis.NewStatusCmd(ctx, "ping")}
+	pipeCtx, _ := logger.BeforeProcessPipeline(ctx, pipe)
+	time.Sleep(10 * time.Millisecond)
+	logger.AfterProcessPipeline(pipeCtx, pipe)
+
+	logs := readTestLogs(t, logfile)
+	if len(logs) != 3 {
+		t.Fatal("invalid logs count", logs)
+	}
+	if logs[0]["level"] != "INFO" {
+		t.Error("fast command should use info level", logs[0])
+	}
+	if logs[1]["level"] != "WARN" || logs[1]["latency_ms"].(float64) < 30 {
+		t.Error("slow command should use warn level", logs[1])
+	}
+	if cost := logs[2]["latency_ms"].(float64); cost < 10 || cost > 1000 {
+		t.Error("invalid pipeline latency", cost)
+	}
+}
+
+func TestRedisLoggerMissingStart(t *testing.T) {
+	logfile := filepath.Join(t.TempDir(), "redis.log")
+	logger, _ := NewRedisLogger(RedisLoggerOptions{OutputPaths: []string{logfile}, DisableCaller: true})
+	cmd := redis.NewStatusCmd(context.Background(), "ping")
+	// 没有经过 BeforeProcess 时不应该 panic
+	if err := logger.AfterProcess(context.Background(), cmd); err != nil {
+		t.Fatal(err)
+	}
+	logs := readTestLogs(t, logfile)
+	if len(logs) != 1 || logs[0]["latency_ms"].(float64) != 0 {
+		t.Error("invalid log", logs)
+	}
+}
+
+func TestRedisLoggerConcurrentGinContext(t *testing.T) {
+	logfile := filepath.Join(t.TempDir(), "redis.log")
+	logger, _ := NewRedisLogger(RedisLoggerOptions{
+		SlowThreshold: 20 * time.Millisecond,
+		OutputPaths:   []string{logfile},
+		// 直接调用 hook 时调用栈深度不足
+		DisableCaller: true,
+	})
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	c.Set(string(TraceIDKeyName), "trace-redis")
+
+	// 同一个 gin.Context 上先开始的慢命令不会被后开始的命令覆盖开始时间
+	slow := redis.NewStatusCmd(c, "ping")
+	slowCtx, _ := logger.BeforeProcess(c, slow)
+	time.Sleep(30 * time.Millisecond)
+	fast := redis.NewStatusCmd(c, "ping")
+	fastCtx, _ := logger.BeforeProcess(c, fast)
+	logger.AfterProcess(fastCtx, fast)
+	logger.AfterProcess(slowCtx, slow)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cmd := redis.NewStatusCmd(c, "ping")
+			ctx, _ := logger.BeforeProcess(c, cmd)
+			logger.AfterProcess(ctx, cmd)
+		}()
+	}
+	wg.Wait()
+
+	logs := readTestLogs(t, logfile)
+	if len(logs) != 52 {
+		t.Fatal("invalid logs count", len(logs))
+	}
+	if logs[0]["level"] != "INFO" || logs[1]["level"] != "WARN" {
+		t.Error("commands should be timed independently", logs[0], logs[1])
+	}
+	for _, log := range logs {
+		if log["trace_id"] != "trace-redis" {
+			t.Fatal("trace id should come from gin.Context", log)
+		}
+	}
+}
+
+func TestRedisLoggerWithServer(t *testing.T) {
+	server := miniredis.RunT(t)
+	logfile := filepath.Join(t.TempDir(), "redis.log")
+	logger, _ := NewRedisLogger(RedisLoggerOptions{
+		SlowThreshold: time.Second,
+		OutputPaths:   []string{logfile},
+	})
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+	client.AddHook(logger)
+	ctx := context.Background()
+	client.Set(ctx, "a", 1, 0)
+	client.Get(ctx, "a")
+
+	logs := readTestLogs(t, logfile)
+	if len(logs) != 2 {
+		t.Fatal("invalid logs count", logs)
+	}
+	for _, log := range logs {
+		if log["level"] != "INFO" {
+			t.Error("command should not be slow", log)
+		}
+	}
+}
+
+func TestRedisLoggerPipeline(t *testing.T) {
+	server := miniredis.RunT(t)
+	logfile := filepath.Join(t.TempDir(), "redis.log")
+	logger, _ := NewRedisLogger(RedisLoggerOptions{
+		SlowThreshold:        time.Second,
+		OutputPaths:          []string{logfile},
+		NilErrLevel:          "warn",
+		PipelineErrorDetails: true,
+	})
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+	client.AddHook(logger)
+	ctx := context.Background()
+
+	// 只有 redis.Nil 时使用 NilErrLevel
+	pipe := client.Pipeline()
+	pipe.Set(ctx, "a", 1, 0)
+	pipe.Get(ctx, "missing")
+	_, _ = pipe.Exec(ctx)
+	// 其他错误使用 Error
+	pipe = client.Pipeline()
+	pipe.Get(ctx, "missing")
+	pipe.LPush(ctx, "a", 1)
+	_, _ = pipe.Exec(ctx)
+	tx := client.TxPipeline()
+	tx.Incr(ctx, "b")
+	_, _ = tx.Exec(ctx)
+
+	logs := readTestLogs(t, logfile)
+	var traces, details []map[string]interface{}
+	for _, log := range logs {
+		switch log["msg"] {
+		case "redis trace":
+			traces = append(traces, log)
+		case "redis pipeline cmd":
+			details = append(details, log)
+		}
+	}
+	if len(traces) != 3 || len(details) != 3 {
+		t.Fatal("invalid logs", logs)
+	}
+	if traces[0]["level"] != "WARN" || fmt.Sprint(traces[0]["status"]) != "[ok nil]" || traces[0]["transaction"] != nil {
+		t.Error("invalid nil pipeline log", traces[0])
+	}
+	if caller, _ := traces[0]["caller"].(string); !strings.Contains(caller, "redis_test.go") {
+		t.Error("caller should be application code", caller)
+	}
+	if traces[1]["level"] != "ERROR" || fmt.Sprint(traces[1]["status"]) != "[nil error]" || len(traces[1]["errors"].([]interface{})) != 2 {
+		t.Error("invalid error pipeline log", traces[1])
+	}
+	if traces[2]["level"] != "INFO" || traces[2]["transaction"] != true || fmt.Sprint(traces[2]["status"]) != "[ok ok ok]" {
+		t.Error("invalid transaction log", traces[2])
+	}
+	if details[0]["level"] != "WARN" || details[0]["pipeline_index"] != float64(1) || details[0]["command"] != "get" {
+		t.Error("invalid nil cmd log", details[0])
+	}
+	if details[2]["level"] != "ERROR" || details[2]["pipeline_index"] != float64(1) || details[2]["command"] != "lpush" {
+		t.Error("invalid error cmd log", details[2])
+	}
+}
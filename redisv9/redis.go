@@ -0,0 +1,122 @@
+// Package redisv9 go-redis v9 的日志 hook ，字段和行为与 logit.RedisLogger （go-redis v8）一致
+
+package redisv9
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/feymanlee/logit"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// RedisLogger 使用 zap 打印 go-redis v9 的日志，通过 client.AddHook(logger) 使用
+// 命令和 pipeline 的日志由 logit.RedisLogger 打印，字段和行为与 go-redis v8 相同
+type RedisLogger struct {
+	logger logit.RedisLogger
+}
+
+// NewRedisLogger
+//
+//	@Description: 创建 go-redis v9 的日志 hook ，配置项与 logit.NewRedisLogger 相同
+//	@param opt
+//	@return RedisLogger
+//	@return error
+func NewRedisLogger(opt logit.RedisLoggerOptions) (RedisLogger, error) {
+	logger, err := logit.NewRedisLogger(opt)
+	return RedisLogger{logger: logger.WithNilErr(redis.Nil)}, err
+}
+
+// CtxLogger
+//
+//	@Description: 创建打印日志的 ctx logger
+//	@receiver l
+//	@param ctx
+//	@return *zap.Logger
+func (l RedisLogger) CtxLogger(ctx context.Context) *zap.Logger {
+	return l.logger.CtxLogger(ctx)
+}
+
+// DialHook
+//
+//	@Description: 实现 go-redis v9 Hook DialHook 方法，记录新建连接的地址、耗时和错误
+//	@receiver l
+//	@param next
+//	@return redis.DialHook
+func (l RedisLogger) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		start := time.Now()
+		conn, err := next(ctx, network, addr)
+		fields := []zap.Field{
+			zap.String("network", network),
+			zap.String("addr", addr),
+			zap.Float64("latency_ms", time.Since(start).Seconds()*1e3),
+		}
+		// 连接池建立连接时不经过用户调用，不记录 caller
+		logger := l.CtxLogger(ctx).WithOptions(zap.WithCaller(false))
+		if err != nil {
+			logger.Error("redis dial", append(fields, zap.Error(err))...)
+			return conn, err
+		}
+		if conn.LocalAddr() != nil {
+			fields = append(fields, zap.String("local_addr", conn.LocalAddr().String()))
+		}
+		logger.Debug("redis dial", fields...)
+		return conn, nil
+	}
+}
+
+// ProcessHook
+//
+//	@Description: 实现 go-redis v9 Hook ProcessHook 方法
+//	@receiver l
+//	@param next
+//	@return redis.ProcessHook
+func (l RedisLogger) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmd)
+		// v9 在 hook 返回后才会设置 cmd 的错误，优先使用 hook 返回的错误
+		if err == nil {
+			err = cmd.Err()
+		}
+		l.logger.LogCmd(l.CtxLogger(ctx), cmd, err, time.Since(start))
+		return err
+	}
+}
+
+// ProcessPipelineHook
+//
+//	@Description: 实现 go-redis v9 Hook ProcessPipelineHook 方法
+//	@receiver l
+//	@param next
+//	@return redis.ProcessPipelineHook
+func (l RedisLogger) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmds)
+		pipeline := make([]logit.RedisCmd, len(cmds))
+		for i, cmd := range cmds {
+			pipeline[i] = cmd
+		}
+		// pipeline 比单条命令少一层调用
+		l.logger.LogPipeline(l.CtxLogger(ctx).WithOptions(zap.AddCallerSkip(-1)), pipeline, time.Since(start))
+		return err
+	}
+}
+
+// go-redis v9 的内部日志重定向
+var redisLogRedirector = logit.NewRedisLogRedirector(func(logging logit.RedisInternalLogging) {
+	redis.SetLogger(logging)
+})
+
+// RedirectRedisLog
+//
+//	@Description: 将 go-redis v9 的内部日志重定向到 logit ，没有识别到日志级别时使用 Warn 级别
+//	@param logger 为 nil 时使用 logit.CtxLogger(ctx)
+//	@return func() 调用它可以恢复 go-redis 上一次的内部 logger
+func RedirectRedisLog(logger *zap.Logger) func() {
+	return redisLogRedirector.Redirect(logger)
+}
@@ -0,0 +1,186 @@
+package redisv9
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/feymanlee/logit"
+	"github.com/redis/go-redis/v9"
+)
+
+func readLogs(t *testing.T, filename string) []map[string]interface{} {
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var logs []map[string]interface{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		log := map[string]interface{}{}
+		if err := json.Unmarshal(scanner.Bytes(), &log); err != nil {
+			t.Fatal(err)
+		}
+		logs = append(logs, log)
+	}
+	return logs
+}
+
+func TestRedisLogger(t *testing.T) {
+	server := miniredis.RunT(t)
+	logfile := filepath.Join(t.TempDir(), "redis.log")
+	logger, err := NewRedisLogger(logit.RedisLoggerOptions{
+		OutputPaths:   []string{logfile},
+		SlowThreshold: time.Second,
+		NilErrLevel:   "info",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+	client.AddHook(logger)
+
+	ctx := context.WithValue(context.Background(), logit.TraceIDKeyName, "trace-v9")
+	client.Set(ctx, "a", 1, 0)
+	client.Get(ctx, "missing")
+	pipeline := client.Pipeline()
+	pipeline.Incr(ctx, "a")
+	pipeline.LPush(ctx, "a", 1)
+	_, _ = pipeline.Exec(ctx)
+
+	logs := readLogs(t, logfile)
+	var dial, set, get, pipe map[string]interface{}
+	for _, log := range logs {
+		switch {
+		case log["msg"] == "redis dial":
+			dial = log
+		case log["command"] == "set":
+			set = log
+		case log["command"] == "get":
+			get = log
+		case log["pipeline"] == true:
+			pipe = log
+		}
+	}
+	if dial == nil || dial["addr"] != server.Addr() || dial["level"] != "DEBUG" {
+		t.Error("invalid dial log", dial)
+	}
+	if set == nil || set["level"] != "INFO" || set["args"] != "set a 1: OK" || set["trace_id"] != "trace-v9" {
+		t.Error("invalid set log", set)
+	}
+	if caller, _ := set["caller"].(string); !strings.Contains(caller, "redis_test.go") {
+		t.Error("caller should be application code", caller)
+	}
+	if get == nil || get["level"] != "INFO" || get["error"] != "redis: nil" {
+		t.Error("nil error should use NilErrLevel", get)
+	}
+	if pipe == nil || pipe["level"] != "ERROR" || len(pipe["args"].([]interface{})) != 2 || len(pipe["errors"].([]interface{})) != 1 {
+		t.Error("invalid pipeline log", pipe)
+	}
+	if caller, _ := pipe["caller"].(string); !strings.Contains(caller, "redis_test.go") {
+		t.Error("pipeline caller should be application code", caller)
+	}
+}
+
+func TestRedisLoggerTransaction(t *testing.T) {
+	server := miniredis.RunT(t)
+	logfile := filepath.Join(t.TempDir(), "redis.log")
+	logger, _ := NewRedisLogger(logit.RedisLoggerOptions{
+		OutputPaths:          []string{logfile},
+		SlowThreshold:        time.Second,
+		NilErrLevel:          "warn",
+		PipelineErrorDetails: true,
+	})
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+	client.AddHook(logger)
+	ctx := context.Background()
+	tx := client.TxPipeline()
+	tx.Set(ctx, "a", 1, 0)
+	tx.Get(ctx, "missing")
+	_, _ = tx.Exec(ctx)
+
+	var trace, detail map[string]interface{}
+	for _, log := range readLogs(t, logfile) {
+		switch log["msg"] {
+		case "redis trace":
+			trace = log
+		case "redis pipeline cmd":
+			detail = log
+		}
+	}
+	if trace == nil || trace["level"] != "WARN" || trace["transaction"] != true || fmt.Sprint(trace["status"]) != "[ok ok nil ok]" {
+		t.Error("invalid transaction log", trace)
+	}
+	if detail == nil || detail["level"] != "WARN" || detail["pipeline_index"] != float64(2) || detail["command"] != "get" {
+		t.Error("invalid failed cmd log", detail)
+	}
+}
+
+func TestRedisLoggerDialError(t *testing.T) {
+	logfile := filepath.Join(t.TempDir(), "redis.log")
+	logger, err := NewRedisLogger(logit.RedisLoggerOptions{OutputPaths: []string{logfile}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	defer client.Close()
+	client.AddHook(logger)
+	client.Ping(context.Background())
+
+	logs := readLogs(t, logfile)
+	if len(logs) == 0 || logs[0]["msg"] != "redis dial" || logs[0]["level"] != "ERROR" || logs[0]["error"] == nil {
+		t.Fatal("invalid dial error log", logs)
+	}
+}
+
+func TestRedisLoggerStats(t *testing.T) {
+	server := miniredis.RunT(t)
+	stats := logit.NewRedisStats(logit.RedisStatsConfig{LogInterval: -1})
+	logger, err := NewRedisLogger(logit.RedisLoggerOptions{
+		OutputPaths: []string{filepath.Join(t.TempDir(), "redis.log")},
+		Stats:       stats,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+	client.AddHook(logger)
+	ctx := context.Background()
+	client.Get(ctx, "user:1")
+	client.Set(ctx, "user:1", 1, 0)
+	client.LPush(ctx, "user:1", 1)
+
+	summary := stats.Snapshot()
+	var prefix logit.RedisStatsEntry
+	for _, entry := range summary.Prefixes {
+		if entry.Name == "user" {
+			prefix = entry
+		}
+	}
+	// redis.Nil 不计为错误
+	if prefix.Count != 3 || prefix.Errors != 1 {
+		t.Error("invalid stats", summary)
+	}
+}
+
+func TestRedirectRedisLog(t *testing.T) {
+	undo := RedirectRedisLog(nil)
+	if _, ok := redisLogRedirector.Logging().(*logit.RedisLogging); !ok {
+		t.Fatal("redis logging should be redirected")
+	}
+	undo()
+	if _, ok := redisLogRedirector.Logging().(*logit.RedisStdLogging); !ok {
+		t.Error("undo should restore default redis logging")
+	}
+}
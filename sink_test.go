@@ -8,6 +8,7 @@
 package logit
 
 import (
+	"path/filepath"
 	"testing"
 
 	"go.uber.org/zap"
@@ -16,12 +17,13 @@ import (
 
 func TestLumberjackSink(t *testing.T) {
 	scheme := "lumberjack"
-	filename := "test.log"
+	filename := filepath.Join(t.TempDir(), "test.log")
 	maxAge := 1
 	maxBackups := 2
 	maxSize := 1
 
 	lumberjackSink := NewLumberjackSink(filename, maxAge, maxBackups, maxSize, true, true)
+	defer lumberjackSink.Close()
 
 	err := RegisterSink(scheme, lumberjackSink)
 	if err != nil {
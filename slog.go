@@ -0,0 +1,252 @@
+//go:build go1.21
+
+package logit
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// SlogHandler 实现 slog.Handler ，通过 zap logger 打印 slog 的日志
+// slog 的 attr 转换为 zap 字段， group 转换为嵌套的 json 对象， caller 使用调用 slog 的位置
+type SlogHandler struct {
+	// 为 nil 时使用 CtxLogger(ctx)
+	logger *zap.Logger
+	// WithAttrs 和 WithGroup 累积的字段， group 使用 zap.Namespace 表示
+	fields []zap.Field
+	// WithGroup 添加但还没有字段的 group ，之后有字段时才打开 namespace ，避免输出空的 group
+	groups []string
+}
+
+// NewSlogHandler
+//
+//	@Description: 创建 slog.Handler ， logger 为 nil 时使用 CtxLogger(ctx) 打印，与 logit.Info(ctx, ...) 输出相同
+//	否则使用 logger 并添加 ctx 中的 trace id
+//	@param logger
+//	@return *SlogHandler
+func NewSlogHandler(logger *zap.Logger) *SlogHandler {
+	return &SlogHandler{logger: logger}
+}
+
+// NewSlogLogger
+//
+//	@Description: 创建使用 SlogHandler 的 slog.Logger ，可以通过 slog.SetDefault 设置为默认 logger
+//	@param logger 为 nil 时使用 CtxLogger(ctx)
+//	@return *slog.Logger
+func NewSlogLogger(logger *zap.Logger) *slog.Logger {
+	return slog.New(NewSlogHandler(logger))
+}
+
+// ctxLogger
+//
+//	@Description: 获取打印日志的 logger
+//	@receiver h
+//	@param ctx
+//	@return *zap.Logger
+func (h *SlogHandler) ctxLogger(ctx context.Context) *zap.Logger {
+	if h.logger == nil {
+		return CtxLogger(ctx)
+	}
+	_, ctxLogger := NewCtxLogger(ctx, h.logger, "")
+	return ctxLogger
+}
+
+// Enabled
+//
+//	@Description: 实现 slog.Handler 接口方法
+//	@receiver h
+//	@param ctx
+//	@param level
+//	@return bool
+func (h *SlogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	zapLevel := slogZapLevel(level)
+	if h.logger != nil {
+		return h.logger.Core().Enabled(zapLevel)
+	}
+	// 只判断级别，不创建 ctxLogger ， ctx 中没有 ctxLogger 时使用全局 logger 的级别
+	if ctx != nil {
+		if ctxLogger := storedCtxLogger(ctx); ctxLogger != nil {
+			return ctxLogger.Core().Enabled(zapLevel)
+		}
+	}
+	rwMutex.RLock()
+	defer rwMutex.RUnlock()
+	return baseLogger.Core().Enabled(zapLevel)
+}
+
+// Handle
+//
+//	@Description: 实现 slog.Handler 接口方法
+//	@receiver h
+//	@param ctx
+//	@param record
+//	@return error
+func (h *SlogHandler) Handle(ctx context.Context, record slog.Record) error {
+	ce := h.ctxLogger(ctx).Check(slogZapLevel(record.Level), record.Message)
+	if ce == nil {
+		return nil
+	}
+	if !record.Time.IsZero() {
+		ce.Entry.Time = record.Time
+	}
+	// zap 计算的 caller 是 slog 内部的位置，替换为调用 slog 的位置
+	if ce.Entry.Caller.Defined && record.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{record.PC}).Next()
+		ce.Entry.Caller = zapcore.EntryCaller{
+			Defined:  true,
+			PC:       frame.PC,
+			File:     frame.File,
+			Line:     frame.Line,
+			Function: frame.Function,
+		}
+	}
+	var attrFields []zap.Field
+	record.Attrs(func(attr slog.Attr) bool {
+		attrFields = appendSlogAttr(attrFields, attr)
+		return true
+	})
+	ce.Write(h.withFields(attrFields)...)
+	return nil
+}
+
+// withFields
+//
+//	@Description: 在累积的字段后追加字段，有字段时先打开还没有字段的 group
+//	@receiver h
+//	@param fields
+//	@return []zap.Field
+func (h *SlogHandler) withFields(fields []zap.Field) []zap.Field {
+	if len(fields) == 0 {
+		return h.fields
+	}
+	all := make([]zap.Field, 0, len(h.fields)+len(h.groups)+len(fields))
+	all = append(all, h.fields...)
+	for _, group := range h.groups {
+		all = append(all, zap.Namespace(group))
+	}
+	return append(all, fields...)
+}
+
+// WithAttrs
+//
+//	@Description: 实现 slog.Handler 接口方法
+//	@receiver h
+//	@param attrs
+//	@return slog.Handler
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var attrFields []zap.Field
+	for _, attr := range attrs {
+		attrFields = appendSlogAttr(attrFields, attr)
+	}
+	if len(attrFields) == 0 {
+		return h
+	}
+	return &SlogHandler{logger: h.logger, fields: h.withFields(attrFields)}
+}
+
+// WithGroup
+//
+//	@Description: 实现 slog.Handler 接口方法，之后的字段都记录在 name 对象中，没有字段时不输出 name 对象
+//	@receiver h
+//	@param name
+//	@return slog.Handler
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	groups := make([]string, 0, len(h.groups)+1)
+	groups = append(groups, h.groups...)
+	return &SlogHandler{logger: h.logger, fields: h.fields, groups: append(groups, name)}
+}
+
+// slogZapLevel
+//
+//	@Description: slog 日志级别转换为 zap 日志级别，自定义级别向下取最近的标准级别，高于 Error 的级别使用 Error
+//	@param level
+//	@return zapcore.Level
+func slogZapLevel(level slog.Level) zapcore.Level {
+	switch {
+	case level < slog.LevelInfo:
+		return zap.DebugLevel
+	case level < slog.LevelWarn:
+		return zap.InfoLevel
+	case level < slog.LevelError:
+		return zap.WarnLevel
+	default:
+		return zap.ErrorLevel
+	}
+}
+
+// appendSlogAttr
+//
+//	@Description: 将 slog attr 转换为 zap 字段，忽略空 attr ，空 key 的 group 展开到当前层级
+//	@param fields
+//	@param attr
+//	@return []zap.Field
+func appendSlogAttr(fields []zap.Field, attr slog.Attr) []zap.Field {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return fields
+	}
+	if attr.Value.Kind() != slog.KindGroup {
+		return append(fields, slogField(attr))
+	}
+	group := attr.Value.Group()
+	if len(group) == 0 {
+		return fields
+	}
+	if attr.Key == "" {
+		for _, a := range group {
+			fields = appendSlogAttr(fields, a)
+		}
+		return fields
+	}
+	return append(fields, zap.Object(attr.Key, slogGroup(group)))
+}
+
+// slogField
+//
+//	@Description: 将非 group 的 slog attr 转换为 zap 字段
+//	@param attr
+//	@return zap.Field
+func slogField(attr slog.Attr) zap.Field {
+	v := attr.Value
+	switch v.Kind() {
+	case slog.KindString:
+		return zap.String(attr.Key, v.String())
+	case slog.KindInt64:
+		return zap.Int64(attr.Key, v.Int64())
+	case slog.KindUint64:
+		return zap.Uint64(attr.Key, v.Uint64())
+	case slog.KindFloat64:
+		return zap.Float64(attr.Key, v.Float64())
+	case slog.KindBool:
+		return zap.Bool(attr.Key, v.Bool())
+	case slog.KindDuration:
+		return zap.Duration(attr.Key, v.Duration())
+	case slog.KindTime:
+		return zap.Time(attr.Key, v.Time())
+	default:
+		return zap.Any(attr.Key, v.Any())
+	}
+}
+
+// slogGroup slog group 的 zap 对象编码
+type slogGroup []slog.Attr
+
+// MarshalLogObject
+//
+//	@Description: 实现 zapcore.ObjectMarshaler 接口方法
+//	@receiver g
+//	@param enc
+//	@return error
+func (g slogGroup) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, field := range appendSlogAttr(nil, slog.Attr{Key: "", Value: slog.GroupValue(g...)}) {
+		field.AddTo(enc)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+//go:build go1.21
+
+package logit
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestSlogHandlerSameAsLogit(t *testing.T) {
+	logfile := filepath.Join(t.TempDir(), "slog.log")
+	logger, _ := NewLogger(Options{Level: "debug", Format: "json", OutputPaths: []string{logfile}, DisableStacktrace: true})
+	ctx, _ := NewCtxLogger(context.Background(), logger, "trace-slog")
+
+	Info(ctx, "hello", zap.String("k", "v"), zap.Int64("n", 1))
+	slog.New(NewSlogHandler(nil)).InfoContext(ctx, "hello", "k", "v", "n", 1)
+
+	logs := readTestLogs(t, logfile)
+	if len(logs) != 2 {
+		t.Fatal("invalid logs", logs)
+	}
+	for _, log := range logs {
+		if caller, _ := log["caller"].(string); !strings.Contains(caller, "slog_test.go") {
+			t.Error("caller should be application code", caller)
+		}
+		delete(log, "time")
+		delete(log, "caller")
+	}
+	if !reflect.DeepEqual(logs[0], logs[1]) {
+		t.Error("slog should produce the same log as logit", logs[0], logs[1])
+	}
+}
+
+func TestSlogHandler(t *testing.T) {
+	logfile := filepath.Join(t.TempDir(), "slog.log")
+	zapLogger, _ := NewLogger(Options{Level: "info", Format: "json", OutputPaths: []string{logfile}, DisableStacktrace: true})
+	logger := NewSlogLogger(zapLogger)
+	ctx := context.WithValue(context.Background(), TraceIDKeyName, "trace-slog")
+
+	logger.DebugContext(ctx, "debug should be disabled")
+	logger.With("service", "api").WithGroup("req").With("id", 1).WarnContext(ctx, "warn",
+		slog.Group("user", "name", "a", slog.Group("", "inline", true)),
+		slog.Group("empty"),
+		"latency", time.Second,
+		"err", errors.New("boom"),
+	)
+	logger.Log(ctx, slog.LevelError+4, "above error")
+	logger.Log(ctx, slog.LevelInfo+1, "custom info")
+
+	logs := readTestLogs(t, logfile)
+	if len(logs) != 3 {
+		t.Fatal("invalid logs", logs)
+	}
+	warn := logs[0]
+	req, _ := warn["req"].(map[string]interface{})
+	user, _ := req["user"].(map[string]interface{})
+	if warn["level"] != "WARN" || warn["trace_id"] != "trace-slog" || warn["service"] != "api" || req["id"] != float64(1) {
+		t.Fatal("invalid warn log", warn)
+	}
+	if user["name"] != "a" || user["inline"] != true || req["empty"] != nil || req["err"] != "boom" || req["latency"] == nil {
+		t.Error("invalid group fields", req)
+	}
+	if logs[1]["level"] != "ERROR" || logs[2]["level"] != "INFO" {
+		t.Error("invalid levels", logs[1], logs[2])
+	}
+}
+
+func TestSlogHandlerEmptyGroup(t *testing.T) {
+	logfile := filepath.Join(t.TempDir(), "slog.log")
+	zapLogger, _ := NewLogger(Options{Format: "json", OutputPaths: []string{logfile}})
+	logger := NewSlogLogger(zapLogger)
+
+	logger.WithGroup("g").Info("no attrs")
+	logger.WithGroup("g").WithGroup("h").Info("empty attrs", slog.Group("empty"))
+	logger.WithGroup("g").With().WithGroup("h").Info("nested", "a", 1)
+	logger.WithGroup("g").With("a", 1).WithGroup("h").Info("with attrs")
+
+	logs := readTestLogs(t, logfile)
+	if len(logs) != 4 {
+		t.Fatal("invalid logs", logs)
+	}
+	if logs[0]["g"] != nil || logs[1]["g"] != nil {
+		t.Error("group without attrs should not be logged", logs[0], logs[1])
+	}
+	if g, _ := logs[2]["g"].(map[string]interface{}); g == nil || g["h"].(map[string]interface{})["a"] != float64(1) {
+		t.Error("invalid nested group", logs[2])
+	}
+	if g, _ := logs[3]["g"].(map[string]interface{}); g == nil || g["a"] != float64(1) || g["h"] != nil {
+		t.Error("invalid group with attrs", logs[3])
+	}
+}
+
+func TestSlogHandlerEnabled(t *testing.T) {
+	logger, _ := NewLogger(Options{Level: "info", OutputPaths: []string{filepath.Join(t.TempDir(), "slog.log")}})
+	defer ReplaceLogger(logger)()
+	handler := NewSlogHandler(nil)
+	ctx := context.Background()
+	if handler.Enabled(ctx, slog.LevelDebug) || !handler.Enabled(ctx, slog.LevelInfo) {
+		t.Error("should use the global logger level")
+	}
+	// 判断级别时不创建 ctxLogger
+	if allocs := testing.AllocsPerRun(100, func() { handler.Enabled(ctx, slog.LevelDebug) }); allocs != 0 {
+		t.Error("Enabled should not allocate", allocs)
+	}
+	debugLogger, _ := NewLogger(Options{Level: "debug", OutputPaths: []string{filepath.Join(t.TempDir(), "debug.log")}})
+	ctx, _ = NewCtxLogger(ctx, debugLogger, "trace-slog")
+	if !handler.Enabled(ctx, slog.LevelDebug) {
+		t.Error("should use the ctx logger level")
+	}
+}
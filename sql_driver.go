@@ -0,0 +1,643 @@
+package logit
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// SQLDriverLoggerName database/sql driver logger 名称
+	SQLDriverLoggerName = "sql"
+)
+
+// SQLDriverOptions SQLDriver 支持的配置项字段定义，日志级别和慢查询的处理与 GormLogger 相同
+// sql.ErrNoRows 由 database/sql 的 Row.Scan 返回，不经过 driver ，不会被记录，没有 GormLogger 的 not found 日志级别
+type SQLDriverOptions struct {
+	Name string
+	// 日志级别， Info 记录全部 sql ， Warn 只记录慢查询和错误， Error 只记录错误
+	LogLevel zapcore.Level
+	// CallerSkip，默认跳过 database/sql 和 driver 的栈帧，从调用 database/sql 的应用代码中获取 caller
+	// Optional.
+	CallerSkip int
+	// 慢请求时间阈值 请求处理时间超过该值则使用 Warn 级别打印日志
+	// Optional.
+	SlowThreshold time.Duration
+	// 日志输出路径，默认 []string{"console"}
+	// Optional.
+	OutputPaths []string
+	// 日志初始字段
+	// Optional.
+	InitialFields map[string]interface{}
+	// 是否关闭打印 caller，默认 false
+	// Optional.
+	DisableCaller bool
+	// 是否关闭打印 stack strace，默认 false
+	// Optional.
+	DisableStacktrace bool
+	// 配置日志字段 key 的名称
+	// Optional.
+	EncoderConfig *zapcore.EncoderConfig
+	// 是否不记录 vars 字段中的参数值
+	// Optional.
+	DisableVars bool
+	// 处理 vars 字段中的参数值，如对手机号等敏感信息脱敏，返回值替换原参数值记录到日志中
+	// Optional.
+	VarsRedactor func(ctx context.Context, sql string, vars []interface{}) []interface{}
+	// 是否记录 sql 查询结构的指纹 fingerprint 字段
+	// Optional.
+	EnableFingerprint bool
+}
+
+// sqlDriverLogger 打印 driver 操作日志
+type sqlDriverLogger struct {
+	logLevel          zapcore.Level
+	callerSkip        int
+	slowThreshold     time.Duration
+	disableVars       bool
+	varsRedactor      func(ctx context.Context, sql string, vars []interface{}) []interface{}
+	enableFingerprint bool
+	_logger           *zap.Logger
+}
+
+// newSQLDriverLogger
+//
+//	@Description: 创建 driver 操作日志的 logger
+//	@param opt
+//	@return *sqlDriverLogger
+//	@return error
+func newSQLDriverLogger(opt SQLDriverOptions) (*sqlDriverLogger, error) {
+	name := SQLDriverLoggerName
+	if opt.Name != "" {
+		name = opt.Name
+	}
+	logger, err := NewLogger(Options{
+		Level:             "debug",
+		Format:            "json",
+		OutputPaths:       opt.OutputPaths,
+		InitialFields:     opt.InitialFields,
+		DisableCaller:     opt.DisableCaller,
+		DisableStacktrace: opt.DisableStacktrace,
+		EncoderConfig:     opt.EncoderConfig,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &sqlDriverLogger{
+		logLevel:          opt.LogLevel,
+		callerSkip:        opt.CallerSkip,
+		slowThreshold:     opt.SlowThreshold,
+		disableVars:       opt.DisableVars,
+		varsRedactor:      opt.VarsRedactor,
+		enableFingerprint: opt.EnableFingerprint,
+		_logger:           logger.Named(name),
+	}, nil
+}
+
+// ctxLogger
+//
+//	@Description: 创建 caller 为应用代码的 ctx logger ，只能在 driver 包装方法中通过 log 调用
+//	@receiver l
+//	@param ctx
+//	@return *zap.Logger
+func (l *sqlDriverLogger) ctxLogger(ctx context.Context) *zap.Logger {
+	_, ctxLogger := NewCtxLogger(ctx, l._logger, "")
+	if l.callerSkip != 0 {
+		return ctxLogger.WithOptions(zap.AddCallerSkip(l.callerSkip))
+	}
+	skip, ok := appCallerSkip(2, isSQLDriverFrame)
+	if !ok {
+		return ctxLogger.WithOptions(zap.WithCaller(false))
+	}
+	return ctxLogger.WithOptions(zap.AddCallerSkip(skip))
+}
+
+// log
+//
+//	@Description: 打印一次 driver 操作的日志， driver.ErrSkip 表示 database/sql 会换一种方式执行，不打印日志
+//	@receiver l
+//	@param ctx
+//	@param operation 操作类型： exec 、 query 、 prepare 、 begin 、 commit 、 rollback
+//	@param query sql ，没有时为空
+//	@param args sql 参数
+//	@param begin 开始时间
+//	@param rows 影响行数，小于 0 时不记录
+//	@param err
+func (l *sqlDriverLogger) log(ctx context.Context, operation, query string, args []driver.NamedValue, begin time.Time, rows int64, err error) {
+	if errors.Is(err, driver.ErrSkip) {
+		return
+	}
+	now := time.Now()
+	if stats := CtxQueryStats(ctx); stats != nil && (operation == "exec" || operation == "query") {
+		stats.record("", now.Sub(begin))
+	}
+	latency := now.Sub(begin).Seconds()
+	slow := l.slowThreshold != 0 && latency > l.slowThreshold.Seconds()
+	switch {
+	case l.logLevel > zap.ErrorLevel:
+		return
+	case err == nil && slow && l.logLevel > zap.WarnLevel:
+		return
+	case err == nil && !slow && l.logLevel > zap.InfoLevel:
+		return
+	}
+
+	fields := []zap.Field{zap.String("operation", operation)}
+	if query != "" {
+		fields = append(fields, zap.String("sql", query))
+		if !l.disableVars && len(args) > 0 {
+			vars := make([]interface{}, 0, len(args))
+			for _, arg := range args {
+				vars = append(vars, arg.Value)
+			}
+			if l.varsRedactor != nil {
+				vars = l.varsRedactor(ctx, query, vars)
+			}
+			fields = append(fields, zap.Any("vars", vars))
+		}
+		if l.enableFingerprint {
+			fields = append(fields, zap.String("fingerprint", SQLFingerprint(query)))
+		}
+	}
+	fields = append(fields, zap.Float64("latency", latency))
+	if rows >= 0 {
+		fields = append(fields, zap.Int64("rows", rows))
+	}
+	logger := l.ctxLogger(ctx)
+	switch {
+	case err != nil:
+		logger.Error("sql trace", append(fields, zap.String("error", err.Error()))...)
+	case slow:
+		logger.Warn("sql trace[slow]", append(fields, zap.Float64("threshold", l.slowThreshold.Seconds()))...)
+	default:
+		logger.Info("sql trace", fields...)
+	}
+}
+
+// isSQLDriverFrame
+//
+//	@Description: 是否是 database/sql 、常用 sql 库或 driver 包装自身的栈帧
+//	@param function 栈帧的函数全名
+//	@return bool
+func isSQLDriverFrame(function string) bool {
+	if strings.HasPrefix(function, "database/sql.") || strings.HasPrefix(function, "github.com/jmoiron/sqlx.") {
+		return true
+	}
+	if !strings.HasPrefix(function, logitPkgPath+".") {
+		return false
+	}
+	name := strings.TrimPrefix(strings.TrimPrefix(function, logitPkgPath+"."), "(*")
+	return strings.HasPrefix(name, "sqlDriver") || strings.HasPrefix(name, "SQLDriver")
+}
+
+// appCallerSkip
+//
+//	@Description: 计算打印日志的栈帧到第一个应用代码栈帧的层数
+//	@param skip 打印日志的栈帧到 appCallerSkip 的层数
+//	@param internal 判断栈帧是否需要跳过
+//	@return int
+//	@return bool 没有找到应用代码栈帧时返回 false
+func appCallerSkip(skip int, internal func(function string) bool) (int, bool) {
+	pcs := make([]uintptr, gormCallerMaxDepth)
+	// 跳过 runtime.Callers 和 appCallerSkip ，从打印日志的栈帧开始
+	n := runtime.Callers(skip+1, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for i := 0; ; i++ {
+		frame, more := frames.Next()
+		if !internal(frame.Function) {
+			return i, true
+		}
+		if !more {
+			return 0, false
+		}
+	}
+}
+
+// SQLDriver 包装 database/sql driver ，记录 Exec 、 Query 、 Prepare 、 Begin 、 Commit 、 Rollback 的 sql 、参数、影响行数、耗时和 trace id
+// 通过 RegisterSQLDriver 注册后使用 sql.Open ，或者通过 NewSQLConnector 包装 driver.Connector 后使用 sql.OpenDB
+type SQLDriver struct {
+	driver driver.Driver
+	logger *sqlDriverLogger
+}
+
+// NewSQLDriver
+//
+//	@Description: 包装 driver
+//	@param d
+//	@param opt
+//	@return *SQLDriver
+//	@return error
+func NewSQLDriver(d driver.Driver, opt SQLDriverOptions) (*SQLDriver, error) {
+	logger, err := newSQLDriverLogger(opt)
+	if err != nil {
+		return nil, err
+	}
+	return &SQLDriver{driver: d, logger: logger}, nil
+}
+
+// RegisterSQLDriver
+//
+//	@Description: 包装 driver 并使用 name 注册到 database/sql ，如 RegisterSQLDriver("logit-sqlite3", &sqlite3.SQLiteDriver{}, opt)
+//	@param name
+//	@param d
+//	@param opt
+//	@return error name 已经注册过时返回错误
+func RegisterSQLDriver(name string, d driver.Driver, opt SQLDriverOptions) error {
+	for _, registered := range sql.Drivers() {
+		if registered == name {
+			return fmt.Errorf("sql: driver %s already registered", name)
+		}
+	}
+	wrapped, err := NewSQLDriver(d, opt)
+	if err != nil {
+		return err
+	}
+	sql.Register(name, wrapped)
+	return nil
+}
+
+// Open
+//
+//	@Description: 实现 driver.Driver 接口方法
+//	@receiver d
+//	@param name
+//	@return driver.Conn
+//	@return error
+func (d *SQLDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlDriverConn{conn: conn, logger: d.logger}, nil
+}
+
+// OpenConnector
+//
+//	@Description: 实现 driver.DriverContext 接口方法
+//	@receiver d
+//	@param name
+//	@return driver.Connector
+//	@return error
+func (d *SQLDriver) OpenConnector(name string) (driver.Connector, error) {
+	if dc, ok := d.driver.(driver.DriverContext); ok {
+		connector, err := dc.OpenConnector(name)
+		if err != nil {
+			return nil, err
+		}
+		return &sqlDriverConnector{connector: connector, driver: d}, nil
+	}
+	return &sqlDriverConnector{connector: sqlDriverDSNConnector{name: name, driver: d.driver}, driver: d}, nil
+}
+
+// NewSQLConnector
+//
+//	@Description: 包装 driver.Connector ，用于 sql.OpenDB
+//	@param c
+//	@param opt
+//	@return driver.Connector
+//	@return error
+func NewSQLConnector(c driver.Connector, opt SQLDriverOptions) (driver.Connector, error) {
+	d, err := NewSQLDriver(c.Driver(), opt)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlDriverConnector{connector: c, driver: d}, nil
+}
+
+// sqlDriverDSNConnector 不支持 driver.DriverContext 的 driver 使用 dsn 创建连接
+type sqlDriverDSNConnector struct {
+	name   string
+	driver driver.Driver
+}
+
+// Connect 实现 driver.Connector 接口方法
+func (c sqlDriverDSNConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.name)
+}
+
+// Driver 实现 driver.Connector 接口方法
+func (c sqlDriverDSNConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// sqlDriverConnector 包装 driver.Connector
+type sqlDriverConnector struct {
+	connector driver.Connector
+	driver    *SQLDriver
+}
+
+// Connect 实现 driver.Connector 接口方法
+func (c *sqlDriverConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlDriverConn{conn: conn, logger: c.driver.logger}, nil
+}
+
+// Driver 实现 driver.Connector 接口方法
+func (c *sqlDriverConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// sqlDriverConn 包装 driver.Conn ，底层连接没有实现的可选接口返回 driver.ErrSkip 或默认值，由 database/sql 按默认方式处理
+type sqlDriverConn struct {
+	conn   driver.Conn
+	logger *sqlDriverLogger
+}
+
+// Prepare 实现 driver.Conn 接口方法
+func (c *sqlDriverConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+// PrepareContext 实现 driver.ConnPrepareContext 接口方法
+func (c *sqlDriverConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	begin := time.Now()
+	var stmt driver.Stmt
+	var err error
+	if pc, ok := c.conn.(driver.ConnPrepareContext); ok {
+		stmt, err = pc.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.conn.Prepare(query)
+	}
+	c.logger.log(ctx, "prepare", query, nil, begin, -1, err)
+	if err != nil {
+		return nil, err
+	}
+	wrapped := &sqlDriverStmt{stmt: stmt, conn: c.conn, query: query, logger: c.logger}
+	if _, ok := stmt.(driver.ColumnConverter); ok {
+		return sqlDriverColumnConverterStmt{wrapped}, nil
+	}
+	return wrapped, nil
+}
+
+// Close 实现 driver.Conn 接口方法
+func (c *sqlDriverConn) Close() error {
+	return c.conn.Close()
+}
+
+// Begin 实现 driver.Conn 接口方法
+func (c *sqlDriverConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+// BeginTx 实现 driver.ConnBeginTx 接口方法
+func (c *sqlDriverConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	begin := time.Now()
+	var tx driver.Tx
+	var err error
+	if cbt, ok := c.conn.(driver.ConnBeginTx); ok {
+		tx, err = cbt.BeginTx(ctx, opts)
+	} else if opts.Isolation != driver.IsolationLevel(sql.LevelDefault) {
+		err = errors.New("sql: driver does not support non-default isolation level")
+	} else if opts.ReadOnly {
+		err = errors.New("sql: driver does not support read-only transactions")
+	} else {
+		//nolint:staticcheck // 底层 driver 没有实现 ConnBeginTx
+		tx, err = c.conn.Begin()
+	}
+	c.logger.log(ctx, "begin", "", nil, begin, -1, err)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlDriverTx{tx: tx, ctx: ctx, logger: c.logger}, nil
+}
+
+// ExecContext 实现 driver.ExecerContext 接口方法
+func (c *sqlDriverConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	begin := time.Now()
+	var result driver.Result
+	var err error
+	switch execer := c.conn.(type) {
+	case driver.ExecerContext:
+		result, err = execer.ExecContext(ctx, query, args)
+	case driver.Execer: //nolint:staticcheck // 兼容只实现了 Execer 的 driver
+		var values []driver.Value
+		if values, err = sqlDriverValues(args); err == nil {
+			result, err = execer.Exec(query, values)
+		}
+	default:
+		return nil, driver.ErrSkip
+	}
+	c.logger.log(ctx, "exec", query, args, begin, sqlDriverRowsAffected(result, err), err)
+	return result, err
+}
+
+// QueryContext 实现 driver.QueryerContext 接口方法
+func (c *sqlDriverConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	begin := time.Now()
+	var rows driver.Rows
+	var err error
+	switch queryer := c.conn.(type) {
+	case driver.QueryerContext:
+		rows, err = queryer.QueryContext(ctx, query, args)
+	case driver.Queryer: //nolint:staticcheck // 兼容只实现了 Queryer 的 driver
+		var values []driver.Value
+		if values, err = sqlDriverValues(args); err == nil {
+			rows, err = queryer.Query(query, values)
+		}
+	default:
+		return nil, driver.ErrSkip
+	}
+	c.logger.log(ctx, "query", query, args, begin, -1, err)
+	return rows, err
+}
+
+// Ping 实现 driver.Pinger 接口方法
+func (c *sqlDriverConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+// ResetSession 实现 driver.SessionResetter 接口方法
+func (c *sqlDriverConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+// IsValid 实现 driver.Validator 接口方法
+func (c *sqlDriverConn) IsValid() bool {
+	if validator, ok := c.conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+// CheckNamedValue 实现 driver.NamedValueChecker 接口方法
+func (c *sqlDriverConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := c.conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// sqlDriverStmt 包装 driver.Stmt
+type sqlDriverStmt struct {
+	stmt driver.Stmt
+	// 创建 stmt 的底层连接，底层 stmt 没有实现 driver.NamedValueChecker 时使用连接的检查方法
+	conn   driver.Conn
+	query  string
+	logger *sqlDriverLogger
+}
+
+// Close 实现 driver.Stmt 接口方法
+func (s *sqlDriverStmt) Close() error {
+	return s.stmt.Close()
+}
+
+// NumInput 实现 driver.Stmt 接口方法
+func (s *sqlDriverStmt) NumInput() int {
+	return s.stmt.NumInput()
+}
+
+// Exec 实现 driver.Stmt 接口方法
+func (s *sqlDriverStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), sqlDriverNamedValues(args))
+}
+
+// ExecContext 实现 driver.StmtExecContext 接口方法
+func (s *sqlDriverStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	begin := time.Now()
+	var result driver.Result
+	var err error
+	if sec, ok := s.stmt.(driver.StmtExecContext); ok {
+		result, err = sec.ExecContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = sqlDriverValues(args); err == nil {
+			//nolint:staticcheck // 底层 driver 没有实现 StmtExecContext
+			result, err = s.stmt.Exec(values)
+		}
+	}
+	s.logger.log(ctx, "exec", s.query, args, begin, sqlDriverRowsAffected(result, err), err)
+	return result, err
+}
+
+// Query 实现 driver.Stmt 接口方法
+func (s *sqlDriverStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), sqlDriverNamedValues(args))
+}
+
+// QueryContext 实现 driver.StmtQueryContext 接口方法
+func (s *sqlDriverStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	begin := time.Now()
+	var rows driver.Rows
+	var err error
+	if sqc, ok := s.stmt.(driver.StmtQueryContext); ok {
+		rows, err = sqc.QueryContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = sqlDriverValues(args); err == nil {
+			//nolint:staticcheck // 底层 driver 没有实现 StmtQueryContext
+			rows, err = s.stmt.Query(values)
+		}
+	}
+	s.logger.log(ctx, "query", s.query, args, begin, -1, err)
+	return rows, err
+}
+
+// CheckNamedValue 实现 driver.NamedValueChecker 接口方法
+// 包装的 stmt 总是实现了该接口， database/sql 不会再使用连接的检查方法，需要和 database/sql 一样先 stmt 后连接
+func (s *sqlDriverStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := s.stmt.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	if checker, ok := s.conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// sqlDriverColumnConverterStmt 底层 stmt 实现了 driver.ColumnConverter 时使用，转发参数转换方法
+type sqlDriverColumnConverterStmt struct {
+	*sqlDriverStmt
+}
+
+// ColumnConverter 实现 driver.ColumnConverter 接口方法
+func (s sqlDriverColumnConverterStmt) ColumnConverter(idx int) driver.ValueConverter {
+	//nolint:staticcheck // 转发底层 driver 的参数转换
+	return s.stmt.(driver.ColumnConverter).ColumnConverter(idx)
+}
+
+// sqlDriverTx 包装 driver.Tx ，使用 BeginTx 的 context 记录 Commit 和 Rollback
+type sqlDriverTx struct {
+	tx     driver.Tx
+	ctx    context.Context
+	logger *sqlDriverLogger
+}
+
+// Commit 实现 driver.Tx 接口方法
+func (t *sqlDriverTx) Commit() error {
+	begin := time.Now()
+	err := t.tx.Commit()
+	t.logger.log(t.ctx, "commit", "", nil, begin, -1, err)
+	return err
+}
+
+// Rollback 实现 driver.Tx 接口方法
+func (t *sqlDriverTx) Rollback() error {
+	begin := time.Now()
+	err := t.tx.Rollback()
+	t.logger.log(t.ctx, "rollback", "", nil, begin, -1, err)
+	return err
+}
+
+// sqlDriverValues
+//
+//	@Description: 将 NamedValue 转换为旧版 driver 接口使用的 Value ，不支持命名参数
+//	@param args
+//	@return []driver.Value
+//	@return error
+func sqlDriverValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, errors.New("sql: driver does not support the use of Named Parameters")
+		}
+		values[i] = arg.Value
+	}
+	return values, nil
+}
+
+// sqlDriverNamedValues
+//
+//	@Description: 将旧版 driver 接口使用的 Value 转换为 NamedValue
+//	@param args
+//	@return []driver.NamedValue
+func sqlDriverNamedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return named
+}
+
+// sqlDriverRowsAffected
+//
+//	@Description: 获取 exec 的影响行数，执行失败或 driver 不支持时返回 -1
+//	@param result
+//	@param err
+//	@return int64
+func sqlDriverRowsAffected(result driver.Result, err error) int64 {
+	if err != nil || result == nil {
+		return -1
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return -1
+	}
+	return rows
+}
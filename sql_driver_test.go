@@ -0,0 +1,279 @@
+package logit
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"go.uber.org/zap/zapcore"
+)
+
+func openTestSQLDriver(t *testing.T, opt SQLDriverOptions) (*sql.DB, string) {
+	logfile := filepath.Join(t.TempDir(), "sql.log")
+	opt.OutputPaths = []string{logfile}
+	connector, err := NewSQLConnector(&sqliteDSNConnector{dsn: filepath.Join(t.TempDir(), "sqlite3.db")}, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := sql.OpenDB(connector)
+	t.Cleanup(func() { db.Close() })
+	return db, logfile
+}
+
+// sqliteDSNConnector 测试用的 sqlite3 connector
+type sqliteDSNConnector struct {
+	dsn string
+}
+
+func (c *sqliteDSNConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.Driver().Open(c.dsn)
+}
+
+func (c *sqliteDSNConnector) Driver() driver.Driver {
+	return &sqlite3.SQLiteDriver{}
+}
+
+func TestSQLDriver(t *testing.T) {
+	db, logfile := openTestSQLDriver(t, SQLDriverOptions{
+		LogLevel: zapcore.InfoLevel,
+		VarsRedactor: func(ctx context.Context, sql string, vars []interface{}) []interface{} {
+			if strings.Contains(sql, "password") {
+				return []interface{}{"***"}
+			}
+			return vars
+		},
+		EnableFingerprint: true,
+	})
+	ctx := context.WithValue(context.Background(), TraceIDKeyName, "trace-sql")
+	if _, err := db.ExecContext(ctx, "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, password TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(ctx, "INSERT INTO users (name, password) VALUES (?, ?)", "a", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	var name string
+	if err := db.QueryRowContext(ctx, "SELECT name FROM users WHERE id = ?", 1).Scan(&name); err != nil {
+		t.Fatal(err)
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmt, err := tx.PrepareContext(ctx, "UPDATE users SET name = ? WHERE id = ?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stmt.ExecContext(ctx, "b", 1); err != nil {
+		t.Fatal(err)
+	}
+	stmt.Close()
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	tx, _ = db.BeginTx(ctx, nil)
+	tx.Rollback()
+	if _, err := db.ExecContext(ctx, "SELECT * FROM missing"); err == nil {
+		t.Fatal("missing table should return error")
+	}
+
+	logs := readTestLogs(t, logfile)
+	operations := make([]string, 0, len(logs))
+	for _, log := range logs {
+		operations = append(operations, log["operation"].(string))
+		if log["trace_id"] != "trace-sql" {
+			t.Error("invalid trace id", log)
+		}
+		if caller, _ := log["caller"].(string); !strings.Contains(caller, "sql_driver_test.go") {
+			t.Error("caller should be application code", caller)
+		}
+	}
+	if strings.Join(operations, ",") != "exec,exec,query,begin,prepare,exec,commit,begin,rollback,exec" {
+		t.Fatal("invalid operations", operations)
+	}
+	insert := logs[1]
+	if insert["msg"] != "sql trace" || insert["rows"] != float64(1) || insert["vars"].([]interface{})[0] != "***" || insert["fingerprint"] == nil {
+		t.Error("invalid insert log", insert)
+	}
+	if logs[2]["rows"] != nil || len(logs[2]["vars"].([]interface{})) != 1 {
+		t.Error("invalid query log", logs[2])
+	}
+	if logs[5]["sql"] != "UPDATE users SET name = ? WHERE id = ?" || logs[5]["rows"] != float64(1) {
+		t.Error("invalid stmt exec log", logs[5])
+	}
+	if logs[9]["level"] != "ERROR" || logs[9]["error"] == nil {
+		t.Error("invalid error log", logs[9])
+	}
+}
+
+func TestSQLDriverLevel(t *testing.T) {
+	db, logfile := openTestSQLDriver(t, SQLDriverOptions{LogLevel: zapcore.WarnLevel, SlowThreshold: time.Nanosecond})
+	db.Exec("CREATE TABLE t (id INTEGER)")
+	db.Exec("SELECT * FROM missing")
+	logs := readTestLogs(t, logfile)
+	if len(logs) != 2 || logs[0]["msg"] != "sql trace[slow]" || logs[0]["level"] != "WARN" || logs[1]["level"] != "ERROR" {
+		t.Error("invalid logs", logs)
+	}
+
+	db, logfile = openTestSQLDriver(t, SQLDriverOptions{LogLevel: zapcore.ErrorLevel + 1})
+	db.Exec("SELECT * FROM missing")
+	if logs := readTestLogs(t, logfile); len(logs) != 0 {
+		t.Error("silent level should not log", logs)
+	}
+}
+
+func TestSQLDriverQueryStats(t *testing.T) {
+	db, _ := openTestSQLDriver(t, SQLDriverOptions{LogLevel: zapcore.ErrorLevel})
+	ctx, stats := WithQueryStats(context.Background())
+	db.ExecContext(ctx, "CREATE TABLE t (id INTEGER)")
+	rows, _ := db.QueryContext(ctx, "SELECT * FROM t")
+	rows.Close()
+	if stats.Queries() != 2 {
+		t.Error("queries should be recorded", stats.Queries())
+	}
+}
+
+func TestRegisterSQLDriver(t *testing.T) {
+	logfile := filepath.Join(t.TempDir(), "sql.log")
+	if err := RegisterSQLDriver("logit-sqlite3-test", &sqlite3.SQLiteDriver{}, SQLDriverOptions{OutputPaths: []string{logfile}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterSQLDriver("logit-sqlite3-test", &sqlite3.SQLiteDriver{}, SQLDriverOptions{}); err == nil {
+		t.Error("duplicate name should return error")
+	}
+	db, err := sql.Open("logit-sqlite3-test", filepath.Join(t.TempDir(), "sqlite3.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE t (id INTEGER)"); err != nil {
+		t.Fatal(err)
+	}
+	if logs := readTestLogs(t, logfile); len(logs) != 1 || logs[0]["operation"] != "exec" {
+		t.Error("invalid logs", logs)
+	}
+}
+
+// fakeSQLValue 不是 driver.Value ，只有底层 driver 的检查或转换方法能接受
+type fakeSQLValue struct {
+	v string
+}
+
+// fakeSQLConnector 测试用的 connector ， checker 为 true 时连接实现 driver.NamedValueChecker ， converter 为 true 时 stmt 实现 driver.ColumnConverter
+type fakeSQLConnector struct {
+	checker   bool
+	converter bool
+	args      []driver.Value
+}
+
+func (c *fakeSQLConnector) Connect(context.Context) (driver.Conn, error) {
+	conn := &fakeSQLConn{connector: c}
+	if c.checker {
+		return &fakeSQLCheckerConn{conn}, nil
+	}
+	return conn, nil
+}
+
+func (c *fakeSQLConnector) Driver() driver.Driver {
+	return nil
+}
+
+type fakeSQLConn struct {
+	connector *fakeSQLConnector
+}
+
+func (c *fakeSQLConn) Prepare(string) (driver.Stmt, error) {
+	stmt := &fakeSQLStmt{connector: c.connector}
+	if c.connector.converter {
+		return &fakeSQLConverterStmt{stmt}, nil
+	}
+	return stmt, nil
+}
+
+func (c *fakeSQLConn) Close() error { return nil }
+
+func (c *fakeSQLConn) Begin() (driver.Tx, error) { return nil, driver.ErrSkip }
+
+type fakeSQLCheckerConn struct {
+	*fakeSQLConn
+}
+
+func (c *fakeSQLCheckerConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if v, ok := nv.Value.(fakeSQLValue); ok {
+		nv.Value = "checked:" + v.v
+		return nil
+	}
+	return driver.ErrSkip
+}
+
+type fakeSQLStmt struct {
+	connector *fakeSQLConnector
+}
+
+func (s *fakeSQLStmt) Close() error { return nil }
+
+func (s *fakeSQLStmt) NumInput() int { return 1 }
+
+func (s *fakeSQLStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.connector.args = args
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeSQLStmt) Query([]driver.Value) (driver.Rows, error) { return nil, driver.ErrSkip }
+
+type fakeSQLConverterStmt struct {
+	*fakeSQLStmt
+}
+
+func (s *fakeSQLConverterStmt) ColumnConverter(int) driver.ValueConverter {
+	return fakeSQLConverter{}
+}
+
+type fakeSQLConverter struct{}
+
+func (fakeSQLConverter) ConvertValue(v interface{}) (driver.Value, error) {
+	if v, ok := v.(fakeSQLValue); ok {
+		return "converted:" + v.v, nil
+	}
+	return driver.DefaultParameterConverter.ConvertValue(v)
+}
+
+func TestSQLDriverValueChecker(t *testing.T) {
+	cases := []struct {
+		connector *fakeSQLConnector
+		want      driver.Value
+	}{
+		{&fakeSQLConnector{checker: true}, "checked:a"},
+		{&fakeSQLConnector{converter: true}, "converted:a"},
+		{&fakeSQLConnector{checker: true, converter: true}, "checked:a"},
+	}
+	for _, c := range cases {
+		connector, err := NewSQLConnector(c.connector, SQLDriverOptions{LogLevel: zapcore.ErrorLevel})
+		if err != nil {
+			t.Fatal(err)
+		}
+		db := sql.OpenDB(connector)
+		stmt, err := db.Prepare("INSERT INTO t VALUES (?)")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := stmt.Exec(fakeSQLValue{v: "a"}); err != nil {
+			t.Error("custom value should be accepted by the underlying driver", err)
+		} else if len(c.connector.args) != 1 || c.connector.args[0] != c.want {
+			t.Error("invalid args", c.connector.args, c.want)
+		}
+		stmt.Close()
+		db.Close()
+	}
+
+	connector, _ := NewSQLConnector(&fakeSQLConnector{}, SQLDriverOptions{LogLevel: zapcore.ErrorLevel})
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	if _, err := db.Exec("INSERT INTO t VALUES (?)", fakeSQLValue{v: "a"}); err == nil {
+		t.Error("custom value should be rejected without driver support")
+	}
+}
@@ -0,0 +1,242 @@
+package logit
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+
+	jsoniter "github.com/json-iterator/go"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// logLevelPrefixes 日志内容前缀对应的日志级别， fatal 和 panic 只按 Error 级别打印，不会退出程序
+var logLevelPrefixes = map[string]zapcore.Level{
+	"trace":    zap.DebugLevel,
+	"debug":    zap.DebugLevel,
+	"dbg":      zap.DebugLevel,
+	"info":     zap.InfoLevel,
+	"notice":   zap.InfoLevel,
+	"warn":     zap.WarnLevel,
+	"warning":  zap.WarnLevel,
+	"error":    zap.ErrorLevel,
+	"err":      zap.ErrorLevel,
+	"crit":     zap.ErrorLevel,
+	"critical": zap.ErrorLevel,
+	"fatal":    zap.ErrorLevel,
+	"panic":    zap.ErrorLevel,
+}
+
+// stdLogMutex 保证 RedirectStdLog 备份和恢复标准库 log 设置的原子性
+var stdLogMutex sync.Mutex
+
+// RedirectStdLog
+//
+//	@Description: 将标准库 log 的输出重定向到名称为 name 的 logit logger ，根据日志内容前缀识别日志级别，默认使用 Info 级别
+//	每次打印时通过 CloneLogger(name) 获取 logger ， ReplaceLogger 和 SetLevel 对重定向的日志同样生效
+//	@param name
+//	@return func() 调用它可以恢复标准库 log 原来的输出、 flags 和 prefix
+func RedirectStdLog(name string) func() {
+	stdLogMutex.Lock()
+	defer stdLogMutex.Unlock()
+	prevWriter, prevFlags, prevPrefix := log.Writer(), log.Flags(), log.Prefix()
+	// 时间和文件位置由 logit 记录
+	log.SetFlags(0)
+	log.SetPrefix("")
+	log.SetOutput(&logWriter{name: name, level: zap.InfoLevel})
+	return func() {
+		stdLogMutex.Lock()
+		defer stdLogMutex.Unlock()
+		log.SetOutput(prevWriter)
+		log.SetFlags(prevFlags)
+		log.SetPrefix(prevPrefix)
+	}
+}
+
+// NewLogWriter
+//
+//	@Description: 创建打印到 logger 的 io.Writer ，每次 Write 打印一条日志，根据日志内容前缀识别日志级别
+//	可用于 log.New 、 logrus.SetOutput 等只支持 io.Writer 输出的日志库
+//	@param logger 为 nil 时使用全局 logger
+//	@param level 没有识别到日志级别时使用的级别
+//	@return io.Writer
+func NewLogWriter(logger *zap.Logger, level zapcore.Level) io.Writer {
+	return &logWriter{logger: logger, level: level}
+}
+
+// logWriter 将写入的内容按行打印到 logit logger
+type logWriter struct {
+	// 为 nil 时使用 CloneLogger(name)
+	logger *zap.Logger
+	name   string
+	// 没有识别到日志级别时使用的级别
+	level zapcore.Level
+}
+
+// Write
+//
+//	@Description: 实现 io.Writer 接口方法
+//	@receiver w
+//	@param p
+//	@return int
+//	@return error
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.output(strings.TrimRight(string(p), "\r\n"))
+	return len(p), nil
+}
+
+// output
+//
+//	@Description: 识别日志级别并打印日志， caller 为调用日志库的应用代码
+//	@receiver w
+//	@param msg
+func (w *logWriter) output(msg string) {
+	level, msg := detectLogLevel(msg, w.level)
+	logger := w.logger
+	if logger == nil {
+		logger = CloneLogger(w.name)
+	}
+	if skip, ok := appCallerSkip(1, isStdLogFrame); ok {
+		logger = logger.WithOptions(zap.AddCallerSkip(skip))
+	} else {
+		logger = logger.WithOptions(zap.WithCaller(false))
+	}
+	if ce := logger.Check(level, msg); ce != nil {
+		ce.Write()
+	}
+}
+
+// PrintfLogger printf 风格的 logger 适配器，满足 Printf 、 Print 、 Println 形式的日志接口
+type PrintfLogger struct {
+	w *logWriter
+}
+
+// NewPrintfLogger
+//
+//	@Description: 创建 printf 风格的 logger 适配器，根据日志内容前缀识别日志级别
+//	@param logger 为 nil 时使用全局 logger
+//	@param level 没有识别到日志级别时使用的级别
+//	@return *PrintfLogger
+func NewPrintfLogger(logger *zap.Logger, level zapcore.Level) *PrintfLogger {
+	return &PrintfLogger{w: &logWriter{logger: logger, level: level}}
+}
+
+// Printf
+//
+//	@Description: 按 fmt.Sprintf 格式化并打印日志
+//	@receiver l
+//	@param format
+//	@param v
+func (l *PrintfLogger) Printf(format string, v ...interface{}) {
+	l.w.output(strings.TrimRight(fmt.Sprintf(format, v...), "\r\n"))
+}
+
+// Print
+//
+//	@Description: 按 fmt.Sprint 格式化并打印日志
+//	@receiver l
+//	@param v
+func (l *PrintfLogger) Print(v ...interface{}) {
+	l.w.output(strings.TrimRight(fmt.Sprint(v...), "\r\n"))
+}
+
+// Println
+//
+//	@Description: 按 fmt.Sprintln 格式化并打印日志
+//	@receiver l
+//	@param v
+func (l *PrintfLogger) Println(v ...interface{}) {
+	l.w.output(strings.TrimRight(fmt.Sprintln(v...), "\r\n"))
+}
+
+// detectLogLevel
+//
+//	@Description: 根据日志内容前缀识别日志级别，支持 [ERROR] xxx 、 error: xxx 、 WARN xxx 等形式，识别到时去掉前缀
+//	没有前缀时识别 logfmt 和 json 格式中的 level 字段，如 logrus 的输出，日志内容保持不变
+//	@param msg
+//	@param level 没有识别到日志级别时使用的级别
+//	@return zapcore.Level
+//	@return string
+func detectLogLevel(msg string, level zapcore.Level) (zapcore.Level, string) {
+	trimmed := strings.TrimLeft(msg, " ")
+	var word, rest string
+	if strings.HasPrefix(trimmed, "{") {
+		// json 格式，如 logrus 的 JSONFormatter
+		if prefixLevel, ok := logLevelPrefixes[strings.ToLower(jsoniter.Get([]byte(trimmed), "level").ToString())]; ok {
+			return prefixLevel, msg
+		}
+		return level, msg
+	}
+	if strings.HasPrefix(trimmed, "[") {
+		end := strings.IndexByte(trimmed, ']')
+		if end < 0 {
+			return level, msg
+		}
+		word, rest = trimmed[1:end], trimmed[end+1:]
+	} else {
+		end := strings.IndexAny(trimmed, ": ")
+		if end <= 0 {
+			return logfmtLevel(msg, level)
+		}
+		word, rest = trimmed[:end], trimmed[end:]
+		// 没有冒号时只识别全大写的前缀，避免把 "Error connecting ..." 这样的普通句子当作级别
+		if rest[0] == ':' {
+			rest = rest[1:]
+		} else if word != strings.ToUpper(word) {
+			return logfmtLevel(msg, level)
+		}
+	}
+	prefixLevel, ok := logLevelPrefixes[strings.ToLower(word)]
+	if !ok {
+		return logfmtLevel(msg, level)
+	}
+	return prefixLevel, strings.TrimLeft(rest, " ")
+}
+
+// logfmtLevel
+//
+//	@Description: 识别 logfmt 格式（如 logrus 的 TextFormatter ： time="..." level=error msg="..."）中 level 字段的日志级别
+//	日志内容保持不变，保留其中的其他字段，引号内的内容不作为字段
+//	@param msg
+//	@param level 没有识别到日志级别时使用的级别
+//	@return zapcore.Level
+//	@return string
+func logfmtLevel(msg string, level zapcore.Level) (zapcore.Level, string) {
+	quoted := false
+	for i := 0; i < len(msg); i++ {
+		switch c := msg[i]; {
+		case c == '\\' && quoted:
+			i++
+		case c == '"':
+			quoted = !quoted
+		case !quoted && (i == 0 || msg[i-1] == ' ') && strings.HasPrefix(msg[i:], "level="):
+			value := msg[i+len("level="):]
+			if end := strings.IndexByte(value, ' '); end >= 0 {
+				value = value[:end]
+			}
+			if prefixLevel, ok := logLevelPrefixes[strings.ToLower(strings.Trim(value, `"`))]; ok {
+				return prefixLevel, msg
+			}
+			return level, msg
+		}
+	}
+	return level, msg
+}
+
+// isStdLogFrame
+//
+//	@Description: 是否是标准库 log 、 logrus 或 logit 日志适配器的栈帧
+//	@param function 栈帧的函数全名
+//	@return bool
+func isStdLogFrame(function string) bool {
+	if strings.HasPrefix(function, "log.") || strings.HasPrefix(function, "github.com/sirupsen/logrus.") {
+		return true
+	}
+	if !strings.HasPrefix(function, logitPkgPath+".") {
+		return false
+	}
+	name := strings.TrimPrefix(strings.TrimPrefix(function, logitPkgPath+"."), "(*")
+	return strings.HasPrefix(name, "logWriter") || strings.HasPrefix(name, "PrintfLogger")
+}
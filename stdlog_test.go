@@ -0,0 +1,101 @@
+package logit
+
+import (
+	"bytes"
+	"log"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestDetectLogLevel(t *testing.T) {
+	cases := []struct {
+		msg   string
+		level string
+		want  string
+	}{
+		{"[ERROR] boom", "error", "boom"},
+		{"warning: disk full", "warn", "disk full"},
+		{"DEBUG cache miss", "debug", "cache miss"},
+		{"[info]started", "info", "started"},
+		{"Error connecting to db", "warn", "Error connecting to db"},
+		{"[job] done", "warn", "[job] done"},
+		{"fatal: exit", "error", "exit"},
+		{"plain", "warn", "plain"},
+		// logrus TextFormatter 和 JSONFormatter 的输出
+		{`time="2023-04-06T18:06:00+08:00" level=error msg="dial failed" addr=":6379"`, "error", `time="2023-04-06T18:06:00+08:00" level=error msg="dial failed" addr=":6379"`},
+		{`level=warning msg="disk full" path=/data`, "warn", `level=warning msg="disk full" path=/data`},
+		{`time="2023-04-06T18:06:00+08:00" level=debug msg="say \"level=error\""`, "debug", `time="2023-04-06T18:06:00+08:00" level=debug msg="say \"level=error\""`},
+		{`msg="level=error" other=1`, "warn", `msg="level=error" other=1`},
+		{`{"level":"error","msg":"boom","time":"2023-04-06T18:06:00+08:00"}`, "error", `{"level":"error","msg":"boom","time":"2023-04-06T18:06:00+08:00"}`},
+	}
+	for _, c := range cases {
+		level, msg := detectLogLevel(c.msg, zap.WarnLevel)
+		if level.String() != c.level || msg != c.want {
+			t.Error("invalid level", c.msg, level, msg)
+		}
+	}
+}
+
+func TestRedirectStdLog(t *testing.T) {
+	logfile := filepath.Join(t.TempDir(), "stdlog.log")
+	logger, _ := NewLogger(Options{Level: "info", Format: "json", OutputPaths: []string{logfile}})
+	defer ReplaceLogger(logger)()
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	log.SetPrefix("app: ")
+	defer log.SetOutput(log.Writer())
+
+	undo := RedirectStdLog("std")
+	log.Println("[ERROR] boom")
+	log.Printf("hello %s", "world")
+	log.Print("[debug] hidden")
+	undo()
+	log.Print("restored")
+
+	logs := readTestLogs(t, logfile)
+	if len(logs) != 2 {
+		t.Fatal("invalid logs", logs)
+	}
+	if logs[0]["level"] != "ERROR" || logs[0]["msg"] != "boom" || logs[0]["logger"] != "logit.std" || logs[1]["level"] != "INFO" || logs[1]["msg"] != "hello world" {
+		t.Error("invalid std logs", logs)
+	}
+	for _, l := range logs {
+		if caller, _ := l["caller"].(string); !strings.Contains(caller, "stdlog_test.go") {
+			t.Error("caller should be application code", caller)
+		}
+	}
+	if log.Prefix() != "app: " || !strings.Contains(buf.String(), "app: ") || !strings.Contains(buf.String(), "restored") {
+		t.Error("undo should restore std log", buf.String())
+	}
+	log.SetPrefix("")
+}
+
+func TestPrintfLogger(t *testing.T) {
+	logfile := filepath.Join(t.TempDir(), "printf.log")
+	logger, _ := NewLogger(Options{Format: "json", OutputPaths: []string{logfile}})
+	printf := NewPrintfLogger(logger, zap.WarnLevel)
+	printf.Printf("retry %d\n", 3)
+	printf.Println("error:", "failed")
+	log.New(NewLogWriter(logger, zap.InfoLevel), "", 0).Print("from writer")
+	// logrus.SetOutput(NewLogWriter(...)) 时写入的 TextFormatter 输出
+	NewLogWriter(logger, zap.InfoLevel).Write([]byte(`time="2023-04-06T18:06:00+08:00" level=error msg="from logrus"` + "\n"))
+
+	logs := readTestLogs(t, logfile)
+	if len(logs) != 4 {
+		t.Fatal("invalid logs", logs)
+	}
+	if logs[0]["level"] != "WARN" || logs[0]["msg"] != "retry 3" || logs[1]["level"] != "ERROR" || logs[1]["msg"] != "failed" || logs[2]["level"] != "INFO" {
+		t.Error("invalid printf logs", logs)
+	}
+	if logs[3]["level"] != "ERROR" {
+		t.Error("logrus level should be detected", logs[3])
+	}
+	for _, l := range logs {
+		if caller, _ := l["caller"].(string); !strings.Contains(caller, "stdlog_test.go") {
+			t.Error("caller should be application code", caller)
+		}
+	}
+}